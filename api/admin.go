@@ -0,0 +1,183 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminMiddleware 管理接口鉴权
+// 只接受 config.AdminConfig 中的管理员账号: HTTP Basic 认证或以管理员身份登录的 session，
+// 未配置管理员账号时管理接口不可用
+func AdminMiddleware(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, password := cfg.Admin.AdminUsername, cfg.Admin.AdminPassword
+		if username == "" || password == "" {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin credentials are not configured"})
+			return
+		}
+
+		if user, pass, ok := c.Request.BasicAuth(); ok {
+			if secureEqual(user, username) && secureEqual(pass, password) {
+				c.Next()
+				return
+			}
+		} else if user, ok := sessions.Default(c).Get("username").(string); ok && user == username {
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", `Basic realm="javbus-api admin"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+	}
+}
+
+func secureEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// registerAdminRoutes 管理接口
+func registerAdminRoutes(r *gin.RouterGroup) {
+	cache := r.Group("/cache")
+	{
+		cache.GET("/stats", GetCacheStats)
+		cache.GET("/keys", ListCacheKeys)
+		cache.DELETE("/keys", DeleteCacheKeys)
+		cache.GET("/entry", GetCacheEntry)
+		cache.DELETE("/entry", DeleteCacheEntry)
+		cache.POST("/flush", FlushCache)
+		cache.POST("/movies/:id/refresh", RefreshMovieCache)
+	}
+	r.GET("/proxies", GetProxyStatus)
+}
+
+// ProxyPoolStatus 代理池中每个代理的状态
+type ProxyPoolStatus struct {
+	Total   int                   `json:"total"`
+	Healthy int                   `json:"healthy"`
+	Proxies []scraper.ProxyStatus `json:"proxies"`
+}
+
+// GetProxyStatus 代理池详情: 每个代理的地址 (密码已脱敏)、可用状态和最近的错误，没有配置代理时为空列表
+// GET /admin/proxies
+func GetProxyStatus(c *gin.Context) {
+	status := ProxyPoolStatus{Proxies: []scraper.ProxyStatus{}}
+	if JavbusScraper != nil && JavbusScraper.Proxies != nil {
+		pool := JavbusScraper.Proxies
+		status.Total = pool.Len()
+		status.Healthy = pool.Healthy()
+		status.Proxies = pool.Status()
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// GetCacheStats 内存缓存统计: 条目数、估算内存、淘汰次数以及各命名空间的上限
+// GET /admin/cache/stats
+func GetCacheStats(c *gin.Context) {
+	c.JSON(http.StatusOK, scraper.CacheStats())
+}
+
+// ListCacheKeys 按前缀列出缓存项 (内存和数据库)
+// GET /admin/cache/keys?prefix=mag:ABP-123:&limit=100
+func ListCacheKeys(c *gin.Context) {
+	prefix := cachePrefix(c.Query("prefix"))
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 1 || limit > 1000 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+		return
+	}
+
+	entries, truncated, err := scraper.ListCacheEntries(prefix, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prefix": prefix, "entries": entries, "truncated": truncated})
+}
+
+// DeleteCacheKeys 删除以 prefix 开头的缓存项，清空全部缓存请使用 /admin/cache/flush
+// DELETE /admin/cache/keys?prefix=mag:ABP-123:*
+func DeleteCacheKeys(c *gin.Context) {
+	prefix := cachePrefix(c.Query("prefix"))
+	if prefix == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prefix is required"})
+		return
+	}
+
+	result, err := scraper.DeleteCachePrefix(prefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"prefix": prefix, "deleted": result})
+}
+
+// GetCacheEntry 查看单个缓存项的写入时间、剩余有效期和值
+// GET /admin/cache/entry?key=movie:ABP-123
+func GetCacheEntry(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+
+	entry, err := scraper.InspectCacheEntry(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if entry == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteCacheEntry 删除单个缓存项
+// DELETE /admin/cache/entry?key=movie:ABP-123
+func DeleteCacheEntry(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "key is required"})
+		return
+	}
+
+	if err := scraper.DeleteCacheKey(key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"key": key, "success": true})
+}
+
+// FlushCache 清空内存和数据库中的全部缓存
+// POST /admin/cache/flush
+func FlushCache(c *gin.Context) {
+	result, err := scraper.FlushCache()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": result})
+}
+
+// RefreshMovieCache 强制从上游重新获取影片详情并覆盖缓存，同时清除该影片的磁力缓存
+// POST /admin/cache/movies/:id/refresh
+func RefreshMovieCache(c *gin.Context) {
+	movie, err := JavbusScraper.RefreshMovieDetail(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, movie)
+}
+
+// cachePrefix 允许前缀以 * 结尾 (mag:ABP-123:*)，与不带 * 等价
+func cachePrefix(prefix string) string {
+	return strings.TrimSuffix(strings.TrimSpace(prefix), "*")
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/fireinrain/javbus-api/assets"
+	"github.com/fireinrain/javbus-api/metrics"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/memstore"
 	"github.com/gin-gonic/gin"
@@ -24,6 +25,10 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	}
 
 	r := gin.Default()
+	// 统计每个路由的请求数和耗时，需要在注册路由之前挂载
+	r.Use(metrics.GinMiddleware())
+	// handler 通过 c.Error 记录的错误统一转为 JSON 响应
+	r.Use(ErrorMiddleware())
 
 	// 使用内嵌的静态文件系统替代直接文件路径
 	fs := assets.GetFileSystem()
@@ -51,6 +56,12 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		c.Data(http.StatusOK, "text/html; charset=utf-8", content)
 	})
 
+	// 健康检查 (无需鉴权，供 docker / k8s 探针使用)
+	r.GET("/health", Health)
+	r.GET("/ready", Ready)
+	// Prometheus 指标
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// 3. Session 设置 (对应 express-session + memorystore)
 	// 使用 memstore (内存存储)，生产环境建议换成 redis
 	secret := cfg.Auth.JavbusSessionSecret
@@ -159,6 +170,19 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		RegisterRoutes(api, cfg)
 	}
 
+	// 管理接口 (只允许管理员账号，不使用上面的 token / session 鉴权)
+	admin := r.Group("/api/admin")
+	admin.Use(AdminMiddleware(cfg))
+	registerAdminRoutes(admin)
+
+	// Torznab 兼容接口 (使用 apikey 鉴权，不走 session)
+	r.GET("/torznab/api", TorznabAPI(cfg))
+
+	// 本地 webhook 测试接收端 (使用签名校验，不走 session)
+	if cfg.Webhook.Receiver {
+		r.POST("/webhook/receive", ReceiveWebhook)
+	}
+
 	// 404 处理 (NoRoute)
 	r.NoRoute(func(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
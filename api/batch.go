@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/gin-gonic/gin"
+)
+
+// BatchMoviesRequest 批量获取影片详情
+type BatchMoviesRequest struct {
+	IDs         []string `json:"ids" binding:"required,min=1"`          // 上限 consts.BatchMaxIDs
+	Concurrency int      `json:"concurrency" binding:"omitempty,min=1"` // 上限 consts.BatchMaxConcurrency
+	Stream      bool     `json:"stream"`                                // 为 true 时以 NDJSON 逐个输出 (按完成顺序)
+}
+
+// BatchMovieItem 单个 ID 的结果，成功时 Movie 不为空，失败时 Error 不为空
+type BatchMovieItem struct {
+	ID     string             `json:"id"`
+	Status int                `json:"status"` // 单独请求 /movies/:id 时的状态码
+	Movie  *model.MovieDetail `json:"movie,omitempty"`
+	Error  *ErrorResponse     `json:"error,omitempty"`
+}
+
+// BatchMoviesResponse 非流式返回，Results 与去重后的 ids 顺序一致
+type BatchMoviesResponse struct {
+	Results   []BatchMovieItem `json:"results"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+}
+
+// BatchMovieDetails 批量获取影片详情，单个 ID 失败不影响其他 ID
+// POST /movies/batch {"ids": ["SSIS-001", "ABP-123"], "concurrency": 4, "stream": false}
+func BatchMovieDetails(c *gin.Context) {
+	var req BatchMoviesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		HandleValidationError(c, err)
+		return
+	}
+	if len(req.IDs) > consts.BatchMaxIDs || req.Concurrency > consts.BatchMaxConcurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ids must contain at most %d movie ids and concurrency must be at most %d", consts.BatchMaxIDs, consts.BatchMaxConcurrency)})
+		return
+	}
+	ids := scraper.BatchIDs(req.IDs)
+	if len(ids) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ids must contain at least one movie id"})
+		return
+	}
+
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if req.Stream {
+		stream := &ndjsonStream{c: c}
+		stream.start()
+		scraper.BatchMovieDetails(ctx, provider, ids, req.Concurrency, func(result scraper.BatchResult) {
+			if ctx.Err() != nil {
+				return
+			}
+			_ = stream.write(batchItem(result))
+			c.Writer.Flush()
+		})
+		return
+	}
+
+	resp := BatchMoviesResponse{Results: make([]BatchMovieItem, len(ids))}
+	scraper.BatchMovieDetails(ctx, provider, ids, req.Concurrency, func(result scraper.BatchResult) {
+		item := batchItem(result)
+		resp.Results[result.Index] = item
+		if item.Error == nil {
+			resp.Succeeded++
+		} else {
+			resp.Failed++
+		}
+	})
+	if err := ctx.Err(); err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func batchItem(result scraper.BatchResult) BatchMovieItem {
+	item := BatchMovieItem{ID: result.ID, Status: http.StatusOK, Movie: result.Movie}
+	if result.Err != nil {
+		status, code := errorStatus(result.Err)
+		item.Status = status
+		item.Movie = nil
+		item.Error = &ErrorResponse{Error: http.StatusText(status), Code: code, Message: result.Err.Error()}
+	}
+	return item
+}
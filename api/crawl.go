@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/gin-gonic/gin"
+)
+
+// CrawlQuery 多页抓取参数，keyword 不为空时抓取搜索结果，否则按 filterType / filterValue 抓取影片列表
+type CrawlQuery struct {
+	model.GetMoviesQuery
+	Keyword     string `form:"keyword"`
+	MaxPages    int    `form:"maxPages" binding:"omitempty,min=1"`    // 上限 consts.CrawlMaxPages
+	Concurrency int    `form:"concurrency" binding:"omitempty,min=1"` // 上限 consts.CrawlMaxConcurrency
+	Format      string `form:"format" binding:"omitempty,oneof=ndjson sse"`
+}
+
+// CrawlMovies 从 page 开始抓取全部页面，边抓取边输出
+// format=ndjson (默认) 每行一个 crawlRecord: 每个影片 {"movie": {...}}，每页结束时 {"page": {...}}，
+// 最后一行为 {"done": {...}} 或 {"error": {...}}，两者都没有说明连接中途断开；
+// format=sse 或 Accept: text/event-stream 时输出同样内容的 Server-Sent Events: movie / page / done / error
+// 第一页就失败时还没有开始输出，与其他接口一样返回错误状态码
+// GET /movies/crawl?filterType=studio&filterValue=7q&maxPages=20
+func CrawlMovies(c *gin.Context) {
+	var query CrawlQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "messages": []string{"Invalid query parameters"}})
+		return
+	}
+	if query.MaxPages > consts.CrawlMaxPages || query.Concurrency > consts.CrawlMaxConcurrency {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("maxPages must be at most %d and concurrency at most %d", consts.CrawlMaxPages, consts.CrawlMaxConcurrency)})
+		return
+	}
+	if query.Keyword == "" && (query.FilterType == "") != (query.FilterValue == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "filterType and filterValue must be used together"})
+		return
+	}
+
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	sse := query.Format == "sse" || (query.Format == "" && strings.Contains(c.GetHeader("Accept"), "text/event-stream"))
+	var stream crawlStream = &ndjsonStream{c: c}
+	if sse {
+		stream = &sseStream{c: c}
+	}
+
+	opts := scraper.CrawlOptions{MaxPages: query.MaxPages, Concurrency: query.Concurrency}
+	result, err := scraper.CrawlMovies(c.Request.Context(), provider, query.Keyword, &query.GetMoviesQuery, opts, stream.page)
+	if err != nil {
+		if !c.Writer.Written() {
+			c.Error(err)
+			return
+		}
+		status, code := errorStatus(err)
+		stream.error(ErrorResponse{Error: http.StatusText(status), Code: code, Message: err.Error()})
+		return
+	}
+	stream.done(result)
+}
+
+// crawlStream 抓取结果的输出格式，第一次写入时才发送响应头
+type crawlStream interface {
+	page(scraper.CrawlPage) error
+	done(scraper.CrawlResult)
+	error(ErrorResponse)
+}
+
+// crawlProgress 一页抓取完成 (不含影片列表)
+type crawlProgress struct {
+	Page        int  `json:"page"`
+	Movies      int  `json:"movies"`
+	HasNextPage bool `json:"hasNextPage"`
+}
+
+func newCrawlProgress(page scraper.CrawlPage) *crawlProgress {
+	return &crawlProgress{Page: page.Page, Movies: len(page.Movies), HasNextPage: page.HasNextPage}
+}
+
+// crawlRecord NDJSON 的一行，各字段只有一个不为空
+type crawlRecord struct {
+	Movie *model.Movie         `json:"movie,omitempty"`
+	Page  *crawlProgress       `json:"page,omitempty"`
+	Done  *scraper.CrawlResult `json:"done,omitempty"`
+	Error *ErrorResponse       `json:"error,omitempty"`
+}
+
+// ndjsonStream 每行一个 crawlRecord
+type ndjsonStream struct {
+	c *gin.Context
+}
+
+func (s *ndjsonStream) page(page scraper.CrawlPage) error {
+	s.start()
+	for i := range page.Movies {
+		if err := s.write(crawlRecord{Movie: &page.Movies[i]}); err != nil {
+			return err
+		}
+	}
+	if err := s.write(crawlRecord{Page: newCrawlProgress(page)}); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (s *ndjsonStream) done(result scraper.CrawlResult) {
+	// 没有任何结果时同样返回 200，只有 done 一行
+	s.start()
+	_ = s.write(crawlRecord{Done: &result})
+	s.c.Writer.Flush()
+}
+
+func (s *ndjsonStream) error(resp ErrorResponse) {
+	_ = s.write(crawlRecord{Error: &resp})
+	s.c.Writer.Flush()
+}
+
+func (s *ndjsonStream) start() {
+	if !s.c.Writer.Written() {
+		s.c.Header("Content-Type", "application/x-ndjson; charset=utf-8")
+		s.c.Status(http.StatusOK)
+		s.c.Writer.WriteHeaderNow()
+	}
+}
+
+func (s *ndjsonStream) write(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.c.Writer.Write(append(data, '\n'))
+	return err
+}
+
+// sseStream 每个影片一个 movie 事件，每页结束时一个 page 事件 (不含影片列表)，最后是 done 或 error 事件
+type sseStream struct {
+	c *gin.Context
+}
+
+func (s *sseStream) page(page scraper.CrawlPage) error {
+	s.start()
+	for _, movie := range page.Movies {
+		if err := s.event("movie", movie); err != nil {
+			return err
+		}
+	}
+	if err := s.event("page", newCrawlProgress(page)); err != nil {
+		return err
+	}
+	s.c.Writer.Flush()
+	return nil
+}
+
+func (s *sseStream) done(result scraper.CrawlResult) {
+	s.start()
+	_ = s.event("done", result)
+	s.c.Writer.Flush()
+}
+
+func (s *sseStream) error(resp ErrorResponse) {
+	_ = s.event("error", resp)
+	s.c.Writer.Flush()
+}
+
+func (s *sseStream) start() {
+	if !s.c.Writer.Written() {
+		s.c.Header("Content-Type", "text/event-stream; charset=utf-8")
+		s.c.Header("Cache-Control", "no-cache")
+		s.c.Header("X-Accel-Buffering", "no") // 关闭 nginx 缓冲
+		s.c.Status(http.StatusOK)
+		s.c.Writer.WriteHeaderNow()
+	}
+}
+
+func (s *sseStream) event(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.c.Writer, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest 客户端在响应前断开连接 (沿用 nginx 的 499)
+const statusClientClosedRequest = 499
+
+// ErrorResponse 统一的错误响应
+// Error 为 HTTP 状态描述，Code 为便于程序判断的错误类型，Message 为具体原因
+type ErrorResponse struct {
+	Error   string `json:"error"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ErrorMiddleware 统一处理 handler 通过 c.Error 记录且尚未写出响应的错误
+// 根据 scraper 的错误类型返回 404 / 429 / 502 / 503 / 504，其余错误返回 500
+func ErrorMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		err := c.Errors.Last().Err
+		status, code := errorStatus(err)
+
+		var upstream *scraper.UpstreamError
+		if status == http.StatusTooManyRequests && errors.As(err, &upstream) && upstream.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(upstream.RetryAfter.Seconds()))))
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   http.StatusText(status),
+			Code:    code,
+			Message: err.Error(),
+		})
+	}
+}
+
+// errorStatus 错误对应的 HTTP 状态码和错误类型
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, scraper.ErrNotFound):
+		return http.StatusNotFound, "not_found"
+	case errors.Is(err, scraper.ErrRateLimited):
+		return http.StatusTooManyRequests, "rate_limited"
+	case errors.Is(err, scraper.ErrBlocked):
+		return http.StatusServiceUnavailable, "upstream_blocked"
+	case errors.Is(err, scraper.ErrUpstreamTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout, "upstream_timeout"
+	case errors.Is(err, scraper.ErrParse):
+		return http.StatusBadGateway, "parse_error"
+	case errors.Is(err, scraper.ErrUpstream):
+		return http.StatusBadGateway, "upstream_error"
+	case errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, "client_closed"
+	default:
+		return http.StatusInternalServerError, "internal_error"
+	}
+}
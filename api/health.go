@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/gin-gonic/gin"
+)
+
+// accessCheckInterval 就绪检查时，访问检测结果超过该时间会在后台重新检测
+const accessCheckInterval = 5 * time.Minute
+
+// accessChecking 保证同一时间只有一个后台访问检测
+var accessChecking atomic.Bool
+
+// HealthCheck 单项检查结果
+type HealthCheck struct {
+	Status  string `json:"status"` // up / down / unknown
+	Message string `json:"message,omitempty"`
+}
+
+// DatabaseCheck 数据库连通性
+type DatabaseCheck struct {
+	HealthCheck
+	Type      string `json:"type"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+// CacheCheck 内存缓存状态
+type CacheCheck struct {
+	HealthCheck
+	Entries   int   `json:"entries"`
+	Bytes     int64 `json:"bytes"`
+	Evictions int64 `json:"evictions"`
+}
+
+// JavbusCheck 最近一次 JavBus 访问检测结果
+type JavbusCheck struct {
+	HealthCheck
+	Access    bool                   `json:"access"`
+	CheckedAt *time.Time             `json:"checkedAt"`
+	Mirror    string                 `json:"mirror,omitempty"` // 当前使用的镜像
+	Mirrors   []scraper.MirrorStatus `json:"mirrors,omitempty"`
+}
+
+// ProxyCheck 代理池状态，没有配置代理时为直连
+// /ready 无需鉴权，只返回数量，每个代理的详情见 /admin/proxies
+type ProxyCheck struct {
+	HealthCheck
+	Total   int `json:"total"`
+	Healthy int `json:"healthy"`
+}
+
+// RateLimitCheck 上游限流状态，Message 列出处于退避期的域名
+type RateLimitCheck struct {
+	HealthCheck
+	scraper.RateLimiterStatus
+}
+
+// ReadinessResponse /ready 返回体
+type ReadinessResponse struct {
+	Status string `json:"status"` // ok / unavailable
+	Checks struct {
+		Database  DatabaseCheck  `json:"database"`
+		Cache     CacheCheck     `json:"cache"`
+		Javbus    JavbusCheck    `json:"javbus"`
+		Proxy     ProxyCheck     `json:"proxy"`
+		RateLimit RateLimitCheck `json:"ratelimit"`
+	} `json:"checks"`
+	Time time.Time `json:"time"`
+}
+
+// Health 存活检查，进程能响应即返回 200
+// GET /health
+func Health(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok", "time": time.Now()})
+}
+
+// Ready 就绪检查，数据库不可用时返回 503
+// JavBus 是否可访问只做展示，不影响就绪状态 (被墙时服务依然可以返回缓存数据)
+// GET /ready
+func Ready(c *gin.Context) {
+	var resp ReadinessResponse
+	resp.Status = "ok"
+	resp.Time = time.Now()
+
+	// 1. 数据库
+	resp.Checks.Database = checkDatabase(c.Request.Context())
+	if resp.Checks.Database.Status != "up" {
+		resp.Status = "unavailable"
+	}
+
+	// 2. 缓存
+	stats := scraper.CacheStats()
+	resp.Checks.Cache = CacheCheck{
+		HealthCheck: HealthCheck{Status: "up"},
+		Entries:     stats.Entries,
+		Bytes:       stats.Bytes,
+		Evictions:   stats.Evictions,
+	}
+
+	// 3. JavBus 访问状态
+	resp.Checks.Javbus = checkJavbus()
+
+	// 4. 代理池状态，同样只做展示
+	resp.Checks.Proxy = checkProxies()
+
+	// 5. 限流状态，退避中的域名只做展示
+	resp.Checks.RateLimit = checkRateLimiter()
+
+	code := http.StatusOK
+	if resp.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, resp)
+}
+
+func checkDatabase(ctx context.Context) DatabaseCheck {
+	check := DatabaseCheck{}
+	if cachedb.CacheDb == nil {
+		check.Status = "down"
+		check.Message = "database not initialized"
+		return check
+	}
+	check.Type = cachedb.CacheDb.Name()
+
+	sqlDB, err := cachedb.CacheDb.DB()
+	if err != nil {
+		check.Status = "down"
+		check.Message = err.Error()
+		return check
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	start := time.Now()
+	if err := sqlDB.PingContext(ctx); err != nil {
+		check.Status = "down"
+		check.Message = err.Error()
+		return check
+	}
+	check.Status = "up"
+	check.LatencyMs = time.Since(start).Milliseconds()
+	return check
+}
+
+func checkJavbus() JavbusCheck {
+	check := JavbusCheck{HealthCheck: HealthCheck{Status: "unknown"}}
+	if JavbusScraper == nil {
+		check.Message = "scraper not initialized"
+		return check
+	}
+	check.Mirror = JavbusScraper.Mirrors.Current()
+	check.Mirrors = JavbusScraper.Mirrors.Status()
+
+	status, checkedAt := JavbusScraper.LastAccessStatus()
+	// 结果过旧或从未检测时在后台刷新，避免阻塞探针
+	if (status == nil || time.Since(checkedAt) > accessCheckInterval) && accessChecking.CompareAndSwap(false, true) {
+		go func() {
+			defer accessChecking.Store(false)
+			_, _ = JavbusScraper.GetAccessJavbus()
+		}()
+	}
+	if status == nil {
+		check.Message = "not checked yet"
+		return check
+	}
+
+	check.Access = status.Access
+	check.Message = status.Message
+	check.CheckedAt = &checkedAt
+	if status.Access {
+		check.Status = "up"
+	} else {
+		check.Status = "down"
+	}
+	return check
+}
+
+func checkProxies() ProxyCheck {
+	var check ProxyCheck
+	if JavbusScraper == nil || JavbusScraper.Proxies == nil {
+		check.Status = "up"
+		check.Message = "direct connection"
+		return check
+	}
+	pool := JavbusScraper.Proxies
+	check.Total = pool.Len()
+	check.Healthy = pool.Healthy()
+	if check.Healthy > 0 {
+		check.Status = "up"
+	} else {
+		check.Status = "down"
+		check.Message = "all proxies are unhealthy"
+	}
+	return check
+}
+
+func checkRateLimiter() RateLimitCheck {
+	check := RateLimitCheck{HealthCheck: HealthCheck{Status: "up"}}
+	if JavbusScraper == nil || JavbusScraper.Limiter == nil {
+		check.Message = "rate limiter not initialized"
+		check.Hosts = []scraper.HostLimiterStatus{}
+		return check
+	}
+	check.RateLimiterStatus = JavbusScraper.Limiter.Status()
+	var backingOff []string
+	for _, h := range check.Hosts {
+		if h.BackoffUntil != nil {
+			backingOff = append(backingOff, h.Host)
+		}
+	}
+	if len(backingOff) > 0 {
+		check.Message = "backing off: " + strings.Join(backingOff, ", ")
+	}
+	return check
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/library"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	LibraryScanner   *library.Scanner
+	LibraryOrganizer *library.Organizer
+)
+
+// registerLibraryRoutes 本地视频库相关路由
+func registerLibraryRoutes(r *gin.RouterGroup) {
+	lib := r.Group("/library")
+	{
+		lib.GET("", ListLibrary)
+		lib.GET("/scan", GetLibraryScanStatus)
+		lib.POST("/scan", StartLibraryScan)
+		lib.GET("/organize", GetOrganizeJournal)
+		lib.POST("/organize", OrganizeLibrary)
+		lib.POST("/organize/:batchId/undo", UndoOrganize)
+		lib.GET("/:id", GetLibraryItem)
+	}
+}
+
+// ListLibrary 分页浏览视频库
+// GET /library?matched=true&code=ABP-123&keyword=xxx&page=1&pageSize=50
+func ListLibrary(c *gin.Context) {
+	var query struct {
+		Matched  string `form:"matched" binding:"omitempty,oneof=true false"`
+		Code     string `form:"code"`
+		Keyword  string `form:"keyword"`
+		Page     int    `form:"page" binding:"omitempty,min=1"`
+		PageSize int    `form:"pageSize" binding:"omitempty,min=1,max=200"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		HandleValidationError(c, err)
+		return
+	}
+
+	q := cachedb.LibraryQuery{
+		Code:     query.Code,
+		Keyword:  query.Keyword,
+		Page:     query.Page,
+		PageSize: query.PageSize,
+	}
+	if query.Matched != "" {
+		matched := query.Matched == "true"
+		q.Matched = &matched
+	}
+
+	items, total, err := cachedb.ListLibraryItems(q)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":    items,
+		"total":    total,
+		"page":     q.Page,
+		"pageSize": len(items),
+	})
+}
+
+// GetLibraryItem 获取单个视频文件的匹配结果
+// GET /library/:id
+func GetLibraryItem(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	item, err := cachedb.GetLibraryItem(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if item == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// GetLibraryScanStatus 获取扫描进度
+// GET /library/scan
+func GetLibraryScanStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"dirs":   LibraryScanner.Dirs(),
+		"status": LibraryScanner.Status(),
+	})
+}
+
+// StartLibraryScan 在后台开始扫描，force=true 时重新匹配所有文件
+// POST /library/scan
+func StartLibraryScan(c *gin.Context) {
+	if len(LibraryScanner.Dirs()) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no library dirs configured"})
+		return
+	}
+
+	if err := LibraryScanner.Start(c.Query("force") == "true"); err != nil {
+		if errors.Is(err, library.ErrScanRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "status": LibraryScanner.Status()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"status": LibraryScanner.Status()})
+}
+
+// OrganizeLibrary 按模板重命名、移动已匹配的视频文件，dryRun=true 时只返回移动计划
+// POST /library/organize
+func OrganizeLibrary(c *gin.Context) {
+	var opts library.OrganizeOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			HandleValidationError(c, err)
+			return
+		}
+	}
+	if c.Query("dryRun") == "true" {
+		opts.DryRun = true
+	}
+
+	// 扫描过程中文件路径会被写回数据库，不能同时整理
+	if LibraryScanner.Status().Running {
+		c.JSON(http.StatusConflict, gin.H{"error": library.ErrScanRunning.Error()})
+		return
+	}
+
+	result, err := LibraryOrganizer.Organize(opts)
+	if err != nil {
+		writeOrganizeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetOrganizeJournal 列出最近的整理批次，指定 batchId 时返回该批次的全部日志
+// GET /library/organize?batchId=xxx&limit=20
+func GetOrganizeJournal(c *gin.Context) {
+	if batchID := c.Query("batchId"); batchID != "" {
+		entries, err := cachedb.ListJournal(batchID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if len(entries) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"batchId": batchID, "entries": entries})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	batches, err := cachedb.ListOrganizeBatches(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"batches": batches})
+}
+
+// UndoOrganize 撤销一次整理
+// POST /library/organize/:batchId/undo
+func UndoOrganize(c *gin.Context) {
+	if LibraryScanner.Status().Running {
+		c.JSON(http.StatusConflict, gin.H{"error": library.ErrScanRunning.Error()})
+		return
+	}
+
+	result, err := LibraryOrganizer.Undo(c.Param("batchId"))
+	if err != nil {
+		writeOrganizeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func writeOrganizeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, library.ErrOrganizeRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrBatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, library.ErrInvalidTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
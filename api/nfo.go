@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/nfo"
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMovieNFO 导出 Kodi / Jellyfin 可识别的 movie.nfo
+// zip=true 时连同 poster.jpg、fanart.jpg 一起打包返回
+// GET /movies/:id/nfo
+func GetMovieNFO(c *gin.Context) {
+	movieId := c.Param("id")
+
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	detail, err := provider.GetMovieDetail(movieId)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 演员头像需要逐个获取演员信息，失败的演员只保留名字
+	movieType := string(model.MovieTypeNormal)
+	if detail.Producer != nil && strings.HasPrefix(detail.Producer.ID, "uncensored/") {
+		movieType = string(model.MovieTypeUncensored)
+	}
+	stars := make(map[string]*model.StarInfo, len(detail.Stars))
+	for _, star := range detail.Stars {
+		if info, err := provider.GetStarInfo(star.ID, movieType); err == nil {
+			stars[star.ID] = info
+		}
+	}
+
+	nfoData, err := nfo.Marshal(nfo.NewMovie(detail, stars))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("zip") != "true" {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.nfo"`, detail.ID))
+		c.Data(http.StatusOK, "application/xml; charset=utf-8", nfoData)
+		return
+	}
+
+	// 下载图片，不支持下载图片的数据源只打包 nfo
+	images := map[string][]byte{}
+	if fetcher, ok := provider.(scraper.ImageFetcher); ok && detail.Img != "" {
+		referer := fmt.Sprintf("%s/%s", javbusBaseURL(), detail.ID)
+		if data, err := fetcher.GetImage(nfo.PosterURL(detail.Img), referer); err == nil {
+			images[nfo.PosterFileName] = data
+		}
+		if data, err := fetcher.GetImage(detail.Img, referer); err == nil {
+			images[nfo.FanartFileName] = data
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := nfo.WriteZip(&buf, nfoData, images); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, detail.ID))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
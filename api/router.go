@@ -1,11 +1,17 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/library"
 	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/fireinrain/javbus-api/subscription"
 	"github.com/gin-gonic/gin"
 
 	"github.com/fireinrain/javbus-api/model" // 引用你的 model 包
@@ -17,39 +23,104 @@ var JavbusScraper *scraper.JavbusScraper
 // 对应 export default router
 func RegisterRoutes(r *gin.RouterGroup, cfg *config.Config) {
 	//初始化scraper
+	scraper.ConfigureCache(cfg.Cache)
 	javbusScraper := scraper.NewJavbusScraper(cfg)
 	JavbusScraper = javbusScraper
+	//注册数据源，其他数据源也在这里注册
+	scraper.RegisterProvider(javbusScraper)
 
 	//是否可以访问javbus
 	r.GET("/accessJavbus", GetAccessJavbus)
+	//可用的数据源
+	r.GET("/providers", GetProviders)
 
 	//javbus api
 	movies := r.Group("/movies")
 	{
 		movies.GET("/", GetMovies)
 		movies.GET("/search", SearchMovies)
+		movies.GET("/crawl", CrawlMovies)
+		movies.POST("/batch", BatchMovieDetails)
 		movies.GET("/:id", GetMovieDetail)
+		movies.GET("/:id/nfo", GetMovieNFO)
 	}
 
 	// 挂载 /stars 路由组
 	stars := r.Group("/stars")
 	{
+		stars.GET("/", GetStars)
+		stars.GET("/search", SearchStars)
 		stars.GET("/:id", GetStarInfo)
+		stars.GET("/:id/movies", GetStarMovies)
 	}
 
+	// 类别目录
+	r.GET("/genres", GetGenres)
+
 	// 挂载 /magnets 路由组
 	magnets := r.Group("/magnets")
 	{
 		magnets.GET("/:movieId", GetMovieMagnets)
 	}
 
+	// 本地视频库
+	LibraryScanner = library.NewScanner(cfg.Library, javbusScraper)
+	LibraryOrganizer = library.NewOrganizer(cfg.Library, javbusScraper)
+	registerLibraryRoutes(r)
+
+	// 新片订阅
+	SubscriptionService = subscription.NewService(javbusScraper, cfg.Scheduler.SubscriptionPages)
+	registerSubscriptionRoutes(r)
+
+	// 磁力监控与 webhook 通知
+	MagnetWatcher = subscription.NewMagnetWatcher(javbusScraper)
+	setupNotifications(cfg)
+	registerWebhookRoutes(r)
 }
 
 func GetAccessJavbus(c *gin.Context) {
-	resp, _ := JavbusScraper.GetAccessJavbus()
+	resp, _ := JavbusScraper.GetAccessJavbusContext(c.Request.Context())
 	c.JSON(http.StatusOK, resp)
 }
 
+// GetProviders 获取已注册的数据源列表
+// GET /providers
+func GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"default":   scraper.JavbusProviderName,
+		"providers": scraper.ProviderNames(),
+	})
+}
+
+// resolveProvider 根据 ?provider= 参数选择数据源
+// 未知数据源时直接返回 400，调用方只需判断第二个返回值
+// 返回的数据源绑定了请求的 context，客户端断开后停止请求上游
+func resolveProvider(c *gin.Context) (scraper.Provider, bool) {
+	p, err := scraper.GetProvider(c.Query("provider"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "providers": scraper.ProviderNames()})
+		return nil, false
+	}
+	return scraper.WithContext(c.Request.Context(), p), true
+}
+
+// resolveCapability 与 resolveProvider 一样选择数据源，并要求它实现可选接口 T (如 scraper.GenreProvider)
+// 数据源不支持时返回 400，feature 用于错误信息
+func resolveCapability[T any](c *gin.Context, feature string) (T, bool) {
+	var capable T
+	p, err := scraper.GetProvider(c.Query("provider"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "providers": scraper.ProviderNames()})
+		return capable, false
+	}
+	capable, ok := p.(T)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "provider " + p.Name() + " does not support " + feature})
+		return capable, false
+	}
+	return capable, true
+}
+
 // ==========================================
 // Handlers (对应原来的 router.get 回调)
 // ==========================================
@@ -57,6 +128,7 @@ func GetAccessJavbus(c *gin.Context) {
 // GetMovies 获取电影列表
 // GET /movies
 func GetMovies(c *gin.Context) {
+	start := time.Now()
 	var query model.GetMoviesQuery
 	// 对应 validate(moviesPageValidator)
 	// Gin 会自动根据 struct tag 验证参数
@@ -65,20 +137,26 @@ func GetMovies(c *gin.Context) {
 		return
 	}
 
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
 	// 调用 scraper
-	resp, err := JavbusScraper.GetMoviesByPage(&query)
+	resp, err := provider.GetMoviesByPage(&query)
 	if err != nil {
-		c.Error(err) // 记录错误
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err) // 由 ErrorMiddleware 返回错误响应
 		return
 	}
 
+	setCacheHeader(c, scraper.MoviesCacheKey(&query), start)
 	c.JSON(http.StatusOK, resp)
 }
 
 // SearchMovies 搜索电影
 // GET /movies/search
 func SearchMovies(c *gin.Context) {
+	start := time.Now()
 	// 定义搜索专用的 Query 结构体 (继承基础查询)
 	type SearchQuery struct {
 		model.GetMoviesQuery
@@ -91,13 +169,19 @@ func SearchMovies(c *gin.Context) {
 		return
 	}
 
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
 	// 调用 scraper
-	resp, err := JavbusScraper.GetMoviesByKeywordAndPage(strings.TrimSpace(query.Keyword), &query.GetMoviesQuery)
+	keyword := strings.TrimSpace(query.Keyword)
+	resp, err := provider.GetMoviesByKeywordAndPage(keyword, &query.GetMoviesQuery)
 
 	if err != nil {
 		// === 复刻 Node.js 的特殊逻辑 ===
-		// if (e.message.includes('404')) { 返回空列表 }
-		if strings.Contains(err.Error(), "404") {
+		// 没有搜索结果时 JavBus 返回 404，这里返回空列表
+		if errors.Is(err, scraper.ErrNotFound) {
 			// 构造一个空的 SearchMoviesPage 响应
 			emptyResp := model.SearchMoviesPage{
 				MoviesPage: model.MoviesPage{
@@ -115,56 +199,189 @@ func SearchMovies(c *gin.Context) {
 		}
 
 		// 其他错误抛出
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	setCacheHeader(c, scraper.SearchCacheKey(keyword, &query.GetMoviesQuery), start)
 	c.JSON(http.StatusOK, resp)
 }
 
 // GetMovieDetail 获取电影详情
 // GET /movies/:id
 func GetMovieDetail(c *gin.Context) {
+	start := time.Now()
 	movieId := c.Param("id")
 
-	movie, err := JavbusScraper.GetMovieDetail(movieId)
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	movie, err := provider.GetMovieDetail(movieId)
 	if err != nil {
-		// 复刻 404 处理逻辑
-		if strings.Contains(err.Error(), "404") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
+	setCacheHeader(c, scraper.MovieCacheKey(movieId), start)
 	c.JSON(http.StatusOK, movie)
 }
 
 // GetStarInfo 获取演员信息
 // GET /stars/:id
 func GetStarInfo(c *gin.Context) {
+	start := time.Now()
 	starId := c.Param("id")
 
 	// 获取 type 参数 (normal/uncensored)
 	movieType := c.Query("type")
 
-	starInfo, err := JavbusScraper.GetStarInfo(starId, movieType)
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	starInfo, err := provider.GetStarInfo(starId, movieType)
 	if err != nil {
-		if strings.Contains(err.Error(), "404") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		}
+		c.Error(err)
 		return
 	}
 
+	setCacheHeader(c, scraper.StarCacheKey(starId, movieType), start)
 	c.JSON(http.StatusOK, starInfo)
 }
 
+// GetStars 获取演员列表
+// GET /stars?page=1&type=normal
+func GetStars(c *gin.Context) {
+	start := time.Now()
+	var query model.GetStarsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "messages": []string{"Invalid query parameters"}})
+		return
+	}
+
+	provider, ok := resolveCapability[scraper.StarListProvider](c, "star lists")
+	if !ok {
+		return
+	}
+
+	resp, err := provider.GetStarsContext(c.Request.Context(), &query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setCacheHeader(c, scraper.StarsCacheKey(&query), start)
+	c.JSON(http.StatusOK, resp)
+}
+
+// SearchStars 按名字搜索演员
+// GET /stars/search?keyword=xxx&page=1&type=normal
+func SearchStars(c *gin.Context) {
+	start := time.Now()
+	type SearchQuery struct {
+		model.GetStarsQuery
+		Keyword string `form:"keyword" binding:"required"`
+	}
+
+	var query SearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "messages": []string{"Keyword is required"}})
+		return
+	}
+
+	keyword := strings.TrimSpace(query.Keyword)
+	if keyword == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keyword must not be blank", "messages": []string{"Keyword is required"}})
+		return
+	}
+
+	provider, ok := resolveCapability[scraper.StarListProvider](c, "star search")
+	if !ok {
+		return
+	}
+
+	resp, err := provider.SearchStarsContext(c.Request.Context(), keyword, &query.GetStarsQuery)
+	if err != nil {
+		// 没有搜索结果时 JavBus 返回 404，这里返回空列表
+		if errors.Is(err, scraper.ErrNotFound) {
+			c.JSON(http.StatusOK, model.SearchStarsPage{
+				StarsPage: model.StarsPage{
+					Stars:      []model.Star{},
+					Pagination: model.Pagination{CurrentPage: 1, Pages: []int{}},
+				},
+				Keyword: keyword,
+			})
+			return
+		}
+		c.Error(err)
+		return
+	}
+
+	setCacheHeader(c, scraper.StarSearchCacheKey(keyword, &query.GetStarsQuery), start)
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetGenres 获取全部类别，按分组返回，类别 ID 可作为 filterType=genre 的 filterValue
+// GET /genres?type=normal|uncensored
+func GetGenres(c *gin.Context) {
+	start := time.Now()
+	movieType := model.MovieType(c.DefaultQuery("type", string(model.MovieTypeNormal)))
+	if movieType != model.MovieTypeNormal && movieType != model.MovieTypeUncensored {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be normal or uncensored"})
+		return
+	}
+
+	provider, ok := resolveCapability[scraper.GenreProvider](c, "genres")
+	if !ok {
+		return
+	}
+
+	catalog, err := provider.GetGenresContext(c.Request.Context(), movieType)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	setCacheHeader(c, scraper.GenresCacheKey(movieType), start)
+	c.JSON(http.StatusOK, catalog)
+}
+
+// GetStarMovies 获取演员信息和作品，all=true 时返回全部作品
+// GET /stars/:id/movies?page=1&type=normal&magnet=all&all=false
+func GetStarMovies(c *gin.Context) {
+	start := time.Now()
+	starId := c.Param("id")
+	var query model.StarMoviesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "messages": []string{"Invalid query parameters"}})
+		return
+	}
+
+	provider, ok := resolveCapability[scraper.StarMoviesProvider](c, "star filmography")
+	if !ok {
+		return
+	}
+
+	resp, err := provider.GetStarMoviesContext(c.Request.Context(), starId, &query)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	// 全部作品由多页缓存拼接而成，没有单独的缓存项
+	if !query.All {
+		setCacheHeader(c, scraper.StarMoviesCacheKey(starId, &query), start)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
 // GetMovieMagnets 获取磁力链接
 // GET /magnets/:movieId
 func GetMovieMagnets(c *gin.Context) {
+	start := time.Now()
 	movieId := c.Param("movieId")
 
 	// 定义请求参数结构体
@@ -181,11 +398,53 @@ func GetMovieMagnets(c *gin.Context) {
 		return
 	}
 
-	magnets, err := JavbusScraper.GetMovieMagnets(movieId, query.GID, query.UC, query.SortBy, query.SortOrder)
+	provider, ok := resolveProvider(c)
+	if !ok {
+		return
+	}
+
+	magnets, err := provider.GetMovieMagnets(movieId, query.GID, query.UC, query.SortBy, query.SortOrder)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.Error(err)
 		return
 	}
 
+	setCacheHeader(c, scraper.MagnetCacheKey(movieId, query.GID, query.UC, query.SortBy, query.SortOrder), start)
 	c.JSON(http.StatusOK, magnets)
 }
+
+// setCacheHeader 根据缓存中的数据设置响应头，start 为开始处理请求的时间
+// X-Cache: HIT 请求前已在内存缓存中，MISS 本次从 JavBus (或数据库) 加载，STALE 返回的是过期缓存 (上游暂时不可用或正在后台刷新)
+// Cache-Control 的 max-age 为数据写入缓存后的有效期，Age 为已经过的时间
+// 缓存只属于 JavBus 数据源，?provider= 选择其他数据源时不设置
+func setCacheHeader(c *gin.Context, key string, start time.Time) {
+	if name := c.Query("provider"); name != "" && name != scraper.JavbusProviderName {
+		return
+	}
+	info, found := scraper.CacheInfo(key)
+	if !found {
+		c.Header("X-Cache", "MISS")
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case now.After(info.StaleAt):
+		c.Header("X-Cache", "STALE")
+	case info.CreatedAt.Before(start):
+		c.Header("X-Cache", "HIT")
+	default:
+		c.Header("X-Cache", "MISS")
+	}
+	maxAge := int64(info.StaleAt.Sub(info.CreatedAt).Seconds())
+	c.Header("Cache-Control", "private, max-age="+strconv.FormatInt(maxAge, 10))
+	c.Header("Age", strconv.FormatInt(int64(now.Sub(info.CreatedAt).Seconds()), 10))
+}
+
+// javbusBaseURL 对外返回的 JavBus 链接使用的域名
+func javbusBaseURL() string {
+	if JavbusScraper == nil {
+		return consts.JavBusURL
+	}
+	return JavbusScraper.BaseURL()
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/subscription"
+	"github.com/gin-gonic/gin"
+)
+
+var SubscriptionService *subscription.Service
+
+// registerSubscriptionRoutes 新片订阅相关路由
+func registerSubscriptionRoutes(r *gin.RouterGroup) {
+	subs := r.Group("/subscriptions")
+	{
+		subs.GET("", ListSubscriptions)
+		subs.POST("", CreateSubscription)
+		subs.GET("/:id", GetSubscription)
+		subs.DELETE("/:id", DeleteSubscription)
+		subs.POST("/:id/check", CheckSubscription)
+		subs.GET("/:id/new", GetSubscriptionNewMovies)
+		subs.POST("/:id/ack", AckSubscriptionNewMovies)
+	}
+}
+
+// CreateSubscriptionRequest 新建订阅
+type CreateSubscriptionRequest struct {
+	Name        string `json:"name"`
+	FilterType  string `json:"filterType" binding:"required,oneof=star genre director studio label series"`
+	FilterValue string `json:"filterValue" binding:"required"`
+	Type        string `json:"type" binding:"omitempty,oneof=normal uncensored"`
+	Magnet      string `json:"magnet" binding:"omitempty,oneof=all exist"`
+}
+
+// ListSubscriptions 列出全部订阅及未读新片数量
+// GET /subscriptions
+func ListSubscriptions(c *gin.Context) {
+	subs, err := cachedb.ListSubscriptions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subs})
+}
+
+// CreateSubscription 新建订阅，并在后台执行首次检查记录基线
+// POST /subscriptions
+func CreateSubscription(c *gin.Context) {
+	var req CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		HandleValidationError(c, err)
+		return
+	}
+	req.FilterValue = strings.TrimSpace(req.FilterValue)
+	if req.Type == "" {
+		req.Type = "normal"
+	}
+	if req.Magnet == "" {
+		req.Magnet = "all"
+	}
+
+	existing, err := cachedb.FindSubscription(req.FilterType, req.FilterValue, req.Type, req.Magnet)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if existing != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "subscription already exists", "subscription": existing})
+		return
+	}
+
+	sub := &cachedb.Subscription{
+		Name:        req.Name,
+		FilterType:  req.FilterType,
+		FilterValue: req.FilterValue,
+		Type:        req.Type,
+		Magnet:      req.Magnet,
+	}
+	if sub.Name == "" {
+		sub.Name = req.FilterType + ":" + req.FilterValue
+	}
+	if err := cachedb.CreateSubscription(sub); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	baseline := *sub
+	go func() {
+		if _, err := SubscriptionService.Check(&baseline); err != nil {
+			log.Printf("订阅 %s 首次检查失败: %v", baseline.Name, err)
+		}
+	}()
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// GetSubscription 获取单个订阅
+// GET /subscriptions/:id
+func GetSubscription(c *gin.Context) {
+	sub, ok := loadSubscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteSubscription 删除订阅
+// DELETE /subscriptions/:id
+func DeleteSubscription(c *gin.Context) {
+	sub, ok := loadSubscription(c)
+	if !ok {
+		return
+	}
+	if err := cachedb.DeleteSubscription(sub.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CheckSubscription 立即检查一次，返回本次发现的新片
+// POST /subscriptions/:id/check
+func CheckSubscription(c *gin.Context) {
+	sub, ok := loadSubscription(c)
+	if !ok {
+		return
+	}
+	movies, err := SubscriptionService.Check(sub)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "subscription": sub})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscription": sub, "movies": movies})
+}
+
+// GetSubscriptionNewMovies 未读的新片，按发现时间倒序
+// GET /subscriptions/:id/new
+func GetSubscriptionNewMovies(c *gin.Context) {
+	sub, ok := loadSubscription(c)
+	if !ok {
+		return
+	}
+	movies, err := cachedb.ListNewSubscriptionMovies(sub.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscription": sub, "movies": movies})
+}
+
+// AckSubscriptionNewMovies 把新片标记为已读，请求体 {"ids": [...]} 为空时标记全部
+// POST /subscriptions/:id/ack
+func AckSubscriptionNewMovies(c *gin.Context) {
+	sub, ok := loadSubscription(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			HandleValidationError(c, err)
+			return
+		}
+	}
+	acked, err := cachedb.AckSubscriptionMovies(sub.ID, req.IDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"acked": acked})
+}
+
+// loadSubscription 解析路径中的订阅 ID，不存在时直接返回 404
+func loadSubscription(c *gin.Context) (*cachedb.Subscription, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	sub, err := cachedb.GetSubscription(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if sub == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return nil, false
+	}
+	return sub, true
+}
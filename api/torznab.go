@@ -0,0 +1,222 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+	"github.com/fireinrain/javbus-api/torznab"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// javbusPageSize JavBus 列表页每页影片数
+	javbusPageSize = 30
+	// torznabWorkers 并发拉取详情与磁力的协程数
+	torznabWorkers = 4
+)
+
+// TorznabAPI Torznab 兼容接口，供 Sonarr/Radarr/Prowlarr 等工具使用
+// GET /torznab/api?t=caps
+// GET /torznab/api?t=search&q=SSIS-406&apikey=xxx
+// 鉴权使用配置中的 JAVBUS_JWT_TOKEN，通过 apikey 参数或 Authorization 头传入
+func TorznabAPI(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !torznabAuthorized(c, cfg) {
+			writeTorznab(c, torznab.NewError(torznab.ErrorIncorrectCredentials, "Incorrect user credentials"))
+			return
+		}
+
+		switch c.Query("t") {
+		case "caps":
+			writeTorznab(c, torznab.NewCaps("javbus-api"))
+		case "search", "movie":
+			torznabSearch(c)
+		case "":
+			writeTorznab(c, torznab.NewError(torznab.ErrorMissingParameter, "Missing parameter (t)"))
+		default:
+			writeTorznab(c, torznab.NewError(torznab.ErrorUnsupportedFunction, "No such function (%s)", c.Query("t")))
+		}
+	}
+}
+
+// torznabAuthorized 配置了 token 时必须携带正确的 apikey
+// 只开启了账号密码登录时，Torznab 客户端无法完成 session 登录，因此直接拒绝
+func torznabAuthorized(c *gin.Context, cfg *config.Config) bool {
+	apiKey := c.Query("apikey")
+	if apiKey == "" {
+		apiKey = strings.ReplaceAll(c.GetHeader("Authorization"), "Bearer ", "")
+	}
+
+	if cfg.Auth.JavbusJwtToken != "" {
+		return apiKey == cfg.Auth.JavbusJwtToken
+	}
+	useCredentials := cfg.Admin.AdminUsername != "" && cfg.Admin.AdminPassword != ""
+	return !useCredentials
+}
+
+// torznabSearch 搜索影片并展开为磁力结果
+// q 为空时返回首页最新影片，Prowlarr 测试索引器时会发送空搜索
+func torznabSearch(c *gin.Context) {
+	if !torznabCategoryRequested(c.Query("cat")) {
+		writeTorznab(c, torznab.NewFeed("javbus-api", torznabLink(c), 0))
+		return
+	}
+
+	raw, err := scraper.GetProvider(c.Query("provider"))
+	if err != nil {
+		writeTorznab(c, torznab.NewError(torznab.ErrorMissingParameter, "%v", err))
+		return
+	}
+	// 结果需要影片详情页地址
+	linker, ok := raw.(scraper.MovieLinker)
+	if !ok {
+		writeTorznab(c, torznab.NewError(torznab.ErrorUnsupportedFunction, "provider %s does not support torznab", raw.Name()))
+		return
+	}
+	provider := scraper.WithContext(c.Request.Context(), raw)
+
+	offset, _ := strconv.Atoi(c.Query("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 {
+		limit = torznab.DefaultLimit
+	}
+	if limit > torznab.MaxLimit {
+		limit = torznab.MaxLimit
+	}
+
+	keyword := strings.TrimSpace(c.Query("q"))
+	movies, err := torznabMovies(provider, keyword, offset, limit)
+	if err != nil {
+		writeTorznab(c, torznab.NewError(torznab.ErrorUnknown, "%v", err))
+		return
+	}
+
+	feed := torznab.NewFeed("javbus-api", torznabLink(c), offset)
+	for _, items := range torznabItems(provider, linker, movies) {
+		feed.Add(items...)
+	}
+	writeTorznab(c, feed)
+}
+
+// torznabMovies 从 offset 开始取 limit 部影片
+// offset 换算成 JavBus 的页码，一页不够时继续请求后面的页，直到取满或没有下一页；
+// 返回的影片少于 limit 时客户端会认为已经到最后一页
+func torznabMovies(provider scraper.Provider, keyword string, offset, limit int) ([]model.Movie, error) {
+	pageNum := offset/javbusPageSize + 1
+	skip := offset % javbusPageSize
+	var movies []model.Movie
+	for len(movies) < limit {
+		query := &model.GetMoviesQuery{Page: strconv.Itoa(pageNum), Magnet: model.MagnetTypeExist}
+		var page *model.MoviesPage
+		if keyword == "" {
+			p, err := provider.GetMoviesByPage(query)
+			if err != nil {
+				return nil, err
+			}
+			page = p
+		} else {
+			p, err := provider.GetMoviesByKeywordAndPage(keyword, query)
+			// 404 表示没有搜索结果 (或已超过最后一页)
+			if errors.Is(err, scraper.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			page = &p.MoviesPage
+		}
+
+		list := page.Movies
+		list = list[min(skip, len(list)):]
+		skip = 0
+		movies = append(movies, list...)
+		if !page.Pagination.HasNextPage {
+			break
+		}
+		pageNum++
+	}
+	if len(movies) > limit {
+		movies = movies[:limit]
+	}
+	return movies, nil
+}
+
+// torznabItems 并发获取每部影片的详情和磁力链接，结果顺序与 movies 一致
+// 单部影片失败时跳过，不影响其他结果
+func torznabItems(provider scraper.Provider, linker scraper.MovieLinker, movies []model.Movie) [][]torznab.Item {
+	results := make([][]torznab.Item, len(movies))
+	sem := make(chan struct{}, torznabWorkers)
+	var wg sync.WaitGroup
+
+	for i, movie := range movies {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, movie model.Movie) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			detail, err := provider.GetMovieDetail(movie.ID)
+			if err != nil || detail.GID == "" {
+				return
+			}
+			magnets, err := provider.GetMovieMagnets(movie.ID, detail.GID, detail.UC, "", "")
+			if err != nil {
+				return
+			}
+
+			detailLink := linker.MovieURL(movie.ID)
+			items := make([]torznab.Item, 0, len(magnets))
+			for _, magnet := range magnets {
+				items = append(items, torznab.ItemFromMagnet(movie, magnet, detailLink))
+			}
+			results[i] = items
+		}(i, movie)
+	}
+	wg.Wait()
+	return results
+}
+
+// torznabCategoryRequested 判断请求的分类中是否包含 XXX (6000-6999)
+// 未指定 cat 时视为全部分类
+func torznabCategoryRequested(cat string) bool {
+	if strings.TrimSpace(cat) == "" {
+		return true
+	}
+	for _, part := range strings.Split(cat, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && id >= torznab.CategoryXXX && id < torznab.CategoryXXX+1000 {
+			return true
+		}
+	}
+	return false
+}
+
+func torznabLink(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/torznab/api", scheme, c.Request.Host)
+}
+
+// writeTorznab 输出 XML
+// Torznab 约定错误也以 200 + <error> 返回，客户端据此展示错误信息
+func writeTorznab(c *gin.Context, body interface{}) {
+	data, err := xml.MarshalIndent(body, "", "  ")
+	if err != nil {
+		c.String(http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", append([]byte(xml.Header), data...))
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/subscription"
+	"github.com/fireinrain/javbus-api/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	WebhookDispatcher *webhook.Dispatcher
+	WebhookReceiver   *webhook.Receiver
+	MagnetWatcher     *subscription.MagnetWatcher
+)
+
+// registerWebhookRoutes webhook 投递日志与磁力监控相关路由
+func registerWebhookRoutes(r *gin.RouterGroup) {
+	hooks := r.Group("/webhooks")
+	{
+		hooks.GET("/deliveries", ListWebhookDeliveries)
+		hooks.POST("/deliveries/:id/redeliver", RedeliverWebhook)
+		hooks.POST("/test", TestWebhook)
+		hooks.GET("/received", ListReceivedWebhooks)
+	}
+
+	watches := r.Group("/watches")
+	{
+		watches.GET("", ListMagnetWatches)
+		watches.POST("", CreateMagnetWatch)
+		watches.GET("/:id", GetMagnetWatch)
+		watches.DELETE("/:id", DeleteMagnetWatch)
+		watches.POST("/:id/check", CheckMagnetWatch)
+	}
+}
+
+// setupNotifications 创建投递器，并把订阅新片和磁力监控的结果转成 webhook 事件
+func setupNotifications(cfg *config.Config) {
+	WebhookDispatcher = webhook.NewDispatcher(cfg.Webhook)
+	if cfg.Webhook.Receiver {
+		WebhookReceiver = webhook.NewReceiver(cfg.Webhook.Secret)
+	}
+
+	SubscriptionService.OnNewMovies(func(sub cachedb.Subscription, movies []model.Movie) {
+		data := webhook.SubscriptionNewMoviesData{Subscription: sub, Movies: movies}
+		if _, err := WebhookDispatcher.Dispatch(webhook.EventSubscriptionNewMovies, data); err != nil {
+			log.Printf("创建 webhook 投递失败: %v", err)
+		}
+	})
+	MagnetWatcher.OnAvailable(func(watch cachedb.MagnetWatch, magnets []model.Magnet) {
+		data := webhook.MagnetAvailableData{
+			Watch:   watch,
+			Movie:   model.Movie{ID: watch.MovieID, Title: watch.Title, Img: watch.Img, Date: watch.Date, Tags: []string{}},
+			Magnets: magnets,
+		}
+		if _, err := WebhookDispatcher.Dispatch(webhook.EventMagnetAvailable, data); err != nil {
+			log.Printf("创建 webhook 投递失败: %v", err)
+		}
+	})
+}
+
+// ==========================================
+// Webhook
+// ==========================================
+
+// ListWebhookDeliveries 分页查询投递日志
+// GET /webhooks/deliveries?status=failed&event=magnet.available&page=1&pageSize=50
+func ListWebhookDeliveries(c *gin.Context) {
+	var query struct {
+		Status   string `form:"status" binding:"omitempty,oneof=pending success failed"`
+		Event    string `form:"event"`
+		Page     int    `form:"page" binding:"omitempty,min=1"`
+		PageSize int    `form:"pageSize" binding:"omitempty,min=1,max=200"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		HandleValidationError(c, err)
+		return
+	}
+
+	deliveries, total, err := cachedb.ListDeliveries(cachedb.DeliveryQuery{
+		Status:    query.Status,
+		EventType: query.Event,
+		Page:      query.Page,
+		PageSize:  query.PageSize,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries, "total": total})
+}
+
+// RedeliverWebhook 重新投递
+// POST /webhooks/deliveries/:id/redeliver
+func RedeliverWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	delivery, err := WebhookDispatcher.Redeliver(uint(id))
+	if errors.Is(err, webhook.ErrDeliveryPending) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if delivery == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return
+	}
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// TestWebhook 向所有地址发送 ping 事件
+// POST /webhooks/test
+func TestWebhook(c *gin.Context) {
+	if !WebhookDispatcher.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no webhook urls configured"})
+		return
+	}
+	deliveries, err := WebhookDispatcher.Dispatch(webhook.EventPing, gin.H{"message": "pong", "time": time.Now()})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"deliveries": deliveries})
+}
+
+// ReceiveWebhook 本地测试接收端，校验签名后记录事件
+// POST /webhook/receive
+func ReceiveWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	event, err := WebhookReceiver.Receive(c.Request.Header, body)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, webhook.ErrInvalidSignature) || errors.Is(err, webhook.ErrExpiredTimestamp) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("收到 webhook 事件 %s (%s), 签名校验: %v", event.Event, event.Delivery, event.Verified)
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// ListReceivedWebhooks 本地接收端收到的事件
+// GET /webhooks/received
+func ListReceivedWebhooks(c *gin.Context) {
+	if WebhookReceiver == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "webhook receiver is disabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"events": WebhookReceiver.Events()})
+}
+
+// ==========================================
+// 磁力监控
+// ==========================================
+
+// ListMagnetWatches 列出磁力监控
+// GET /watches?fulfilled=false
+func ListMagnetWatches(c *gin.Context) {
+	var query struct {
+		Fulfilled string `form:"fulfilled" binding:"omitempty,oneof=true false"`
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		HandleValidationError(c, err)
+		return
+	}
+	var fulfilled *bool
+	if query.Fulfilled != "" {
+		v := query.Fulfilled == "true"
+		fulfilled = &v
+	}
+
+	watches, err := cachedb.ListMagnetWatches(fulfilled)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"watches": watches})
+}
+
+// CreateMagnetWatch 监控影片的磁力链接
+// POST /watches {"movieId": "SSIS-001", "notifyOn": "any|hd|subtitle"}
+func CreateMagnetWatch(c *gin.Context) {
+	var req struct {
+		MovieID  string `json:"movieId" binding:"required"`
+		NotifyOn string `json:"notifyOn" binding:"omitempty,oneof=any hd subtitle"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		HandleValidationError(c, err)
+		return
+	}
+
+	watch, err := MagnetWatcher.Watch(req.MovieID, req.NotifyOn)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.JSON(http.StatusCreated, watch)
+}
+
+// GetMagnetWatch 获取单个磁力监控
+// GET /watches/:id
+func GetMagnetWatch(c *gin.Context) {
+	watch, ok := loadMagnetWatch(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, watch)
+}
+
+// DeleteMagnetWatch 删除磁力监控
+// DELETE /watches/:id
+func DeleteMagnetWatch(c *gin.Context) {
+	watch, ok := loadMagnetWatch(c)
+	if !ok {
+		return
+	}
+	if err := cachedb.DeleteMagnetWatch(watch.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CheckMagnetWatch 立即检查一次
+// POST /watches/:id/check
+func CheckMagnetWatch(c *gin.Context) {
+	watch, ok := loadMagnetWatch(c)
+	if !ok {
+		return
+	}
+	magnets, err := MagnetWatcher.Check(watch)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error(), "watch": watch})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"watch": watch, "magnets": magnets})
+}
+
+func loadMagnetWatch(c *gin.Context) (*cachedb.MagnetWatch, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return nil, false
+	}
+	watch, err := cachedb.GetMagnetWatch(uint(id))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if watch == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not Found"})
+		return nil, false
+	}
+	return watch, true
+}
@@ -51,10 +51,18 @@ func InitDataBase(cfg config.DatabaseConfig) (*gorm.DB, error) {
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 
-	// 自动迁移 TODO
-	//if err := db.AutoMigrate(&models.User{}); err != nil {
-	//	return nil, err
-	//}
+	// 自动迁移
+	if err := db.AutoMigrate(
+		&ScrapeCache{},
+		&LibraryItem{},
+		&OrganizeJournal{},
+		&Subscription{},
+		&SubscriptionMovie{},
+		&WebhookDelivery{},
+		&MagnetWatch{},
+	); err != nil {
+		return nil, fmt.Errorf("failed to migrate database: %v", err)
+	}
 
 	CacheDb = db
 	return db, nil
@@ -0,0 +1,176 @@
+package cachedb
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LibraryItem 本地视频库中的一个文件及其番号匹配结果
+type LibraryItem struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+	// PathHash 路径的 sha1，用作唯一索引 (MySQL 对长字符串唯一索引有长度限制)
+	PathHash string    `gorm:"size:40;uniqueIndex;not null" json:"-"`
+	Path     string    `gorm:"not null" json:"path"`
+	Dir      string    `json:"dir"`
+	FileName string    `gorm:"size:512" json:"fileName"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+
+	// 从文件名解析出的信息
+	Code       string `gorm:"size:64;index" json:"code"`
+	Part       int    `json:"part"`
+	Subtitle   bool   `json:"subtitle"`
+	Uncensored bool   `json:"uncensored"`
+
+	// 匹配结果
+	Matched   bool       `gorm:"index" json:"matched"`
+	MovieID   string     `gorm:"size:64;index" json:"movieId"`
+	Title     string     `json:"title"`
+	Error     string     `json:"error"`
+	MatchedAt *time.Time `json:"matchedAt"`
+	ScannedAt time.Time  `json:"scannedAt"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// LibraryQuery 视频库列表查询条件
+type LibraryQuery struct {
+	Matched  *bool
+	Code     string
+	Keyword  string
+	Page     int
+	PageSize int
+}
+
+// PathHash 计算路径的 sha1
+func PathHash(path string) string {
+	sum := sha1.Sum([]byte(path))
+	return hex.EncodeToString(sum[:])
+}
+
+// GetLibraryItemByPath 按路径查找，不存在时返回 nil
+func GetLibraryItemByPath(path string) (*LibraryItem, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var item LibraryItem
+	err := CacheDb.Where("path_hash = ?", PathHash(path)).Take(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetLibraryItem 按 ID 查找，不存在时返回 nil
+func GetLibraryItem(id uint) (*LibraryItem, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var item LibraryItem
+	err := CacheDb.Take(&item, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// SaveLibraryItem 新增或更新视频库记录
+func SaveLibraryItem(item *LibraryItem) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	item.PathHash = PathHash(item.Path)
+	return CacheDb.Save(item).Error
+}
+
+// DeleteLibraryItem 删除一条视频库记录
+func DeleteLibraryItem(id uint) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Delete(&LibraryItem{}, id).Error
+}
+
+// ListLibraryItems 分页查询视频库
+func ListLibraryItems(q LibraryQuery) ([]LibraryItem, int64, error) {
+	if CacheDb == nil {
+		return nil, 0, errors.New("database not initialized")
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	tx := CacheDb.Model(&LibraryItem{})
+	if q.Matched != nil {
+		tx = tx.Where("matched = ?", *q.Matched)
+	}
+	if q.Code != "" {
+		tx = tx.Where("code = ?", q.Code)
+	}
+	if q.Keyword != "" {
+		like := "%" + q.Keyword + "%"
+		tx = tx.Where("file_name LIKE ? OR title LIKE ?", like, like)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := []LibraryItem{}
+	err := tx.Order("code, part, id").
+		Offset((q.Page - 1) * q.PageSize).
+		Limit(q.PageSize).
+		Find(&items).Error
+	return items, total, err
+}
+
+// FindMatchedLibraryItems 查询需要整理的已匹配文件，ids 为空时返回全部
+// 指定了部分分段时，同一影片的其他分段也会一起返回
+func FindMatchedLibraryItems(ids []uint) ([]LibraryItem, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+
+	tx := CacheDb.Where("matched = ?", true)
+	if len(ids) > 0 {
+		var movieIDs []string
+		err := CacheDb.Model(&LibraryItem{}).
+			Where("id IN ? AND matched = ?", ids, true).
+			Distinct().Pluck("movie_id", &movieIDs).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(movieIDs) == 0 {
+			return []LibraryItem{}, nil
+		}
+		tx = tx.Where("movie_id IN ?", movieIDs)
+	}
+
+	items := []LibraryItem{}
+	err := tx.Order("movie_id, part, id").Find(&items).Error
+	return items, err
+}
+
+// DeleteMissingLibraryItems 删除本次扫描中未出现的记录 (文件已被删除或移走)
+func DeleteMissingLibraryItems(scannedBefore time.Time) (int64, error) {
+	if CacheDb == nil {
+		return 0, errors.New("database not initialized")
+	}
+	result := CacheDb.Where("scanned_at < ?", scannedBefore).Delete(&LibraryItem{})
+	return result.RowsAffected, result.Error
+}
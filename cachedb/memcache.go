@@ -1,29 +1,102 @@
 package cachedb
 
 import (
+	"container/list"
+	"encoding/json"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/fireinrain/javbus-api/metrics"
 )
 
+// itemOverhead 每个缓存项除了值以外的大致开销 (map 项、链表节点、结构体)
+const itemOverhead = 128
+
+// CacheLimit 缓存上限，0 表示不限制
+type CacheLimit struct {
+	MaxEntries int   `json:"maxEntries"`
+	MaxBytes   int64 `json:"maxBytes"`
+}
+
+// CacheLimits 整体上限和按命名空间 (movie / mag / movies ...) 的上限
+type CacheLimits struct {
+	CacheLimit
+	Namespaces map[string]CacheLimit
+}
+
+// NamespaceStats 单个命名空间的统计
+type NamespaceStats struct {
+	Entries   int        `json:"entries"`
+	Bytes     int64      `json:"bytes"`
+	Evictions int64      `json:"evictions"`
+	Limit     CacheLimit `json:"limit"`
+}
+
+// CacheStats 缓存统计，Bytes 为估算值
+type CacheStats struct {
+	Entries    int                       `json:"entries"`
+	Bytes      int64                     `json:"bytes"`
+	Evictions  int64                     `json:"evictions"`
+	Limit      CacheLimit                `json:"limit"`
+	Namespaces map[string]NamespaceStats `json:"namespaces"`
+}
+
+// EntryInfo 缓存项信息 (不含值)
+type EntryInfo struct {
+	Key       string    `json:"key"`
+	Namespace string    `json:"namespace"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+	StaleAt   time.Time `json:"staleAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 // Cache 内存缓存实现
+// 超过条目数或内存上限时按 LRU 淘汰，命名空间超过自身上限时只淘汰该命名空间中最久未使用的项
 type Cache struct {
-	mu           sync.RWMutex
+	mu           sync.Mutex
 	items        map[string]*cacheItem
+	lru          *list.List // 所有缓存项，最近使用的在前
 	defaultTTL   time.Duration
 	cleanupTimer *time.Ticker
+
+	limits     CacheLimits
+	bytes      int64
+	evictions  int64
+	namespaces map[string]*namespaceState
 }
 
 // cacheItem 缓存项
+// staleAt 之后数据视为过期 (stale)，但在 expiration 之前仍然可以读取
 type cacheItem struct {
+	key        string
+	namespace  string
 	value      interface{}
+	createdAt  int64
+	staleAt    int64
 	expiration int64
+	size       int64
+
+	global *list.Element
+	local  *list.Element
 }
 
-// NewCache 创建新的缓存实例
+// namespaceState 命名空间内的 LRU 链表和统计
+type namespaceState struct {
+	lru       *list.List
+	bytes     int64
+	evictions int64
+}
+
+// NewCache 创建新的缓存实例，默认不限制大小，需要时通过 SetLimits 设置
 func NewCache(defaultTTL time.Duration, cleanupInterval time.Duration) *Cache {
 	c := &Cache{
 		items:      make(map[string]*cacheItem),
+		lru:        list.New(),
 		defaultTTL: defaultTTL,
+		namespaces: make(map[string]*namespaceState),
 	}
 
 	if cleanupInterval > 0 {
@@ -34,6 +107,17 @@ func NewCache(defaultTTL time.Duration, cleanupInterval time.Duration) *Cache {
 	return c
 }
 
+// SetLimits 设置缓存上限，超出的部分立即淘汰
+func (c *Cache) SetLimits(limits CacheLimits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.limits = limits
+	for name := range c.namespaces {
+		c.evictNamespace(name)
+	}
+	c.evictGlobal()
+}
+
 // Set 设置缓存项
 func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	var expiration int64
@@ -42,31 +126,263 @@ func (c *Cache) Set(key string, value interface{}, ttl time.Duration) {
 	} else if c.defaultTTL > 0 {
 		expiration = time.Now().Add(c.defaultTTL).UnixNano()
 	}
+	c.set(key, value, expiration, expiration)
+}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.items[key] = &cacheItem{
+// SetWithStale 设置缓存项，staleAt 之后变为过期数据，expiresAt 之后不可读取
+func (c *Cache) SetWithStale(key string, value interface{}, staleAt, expiresAt time.Time) {
+	c.set(key, value, staleAt.UnixNano(), expiresAt.UnixNano())
+}
+
+func (c *Cache) set(key string, value interface{}, staleAt, expiration int64) {
+	item := &cacheItem{
+		key:        key,
+		namespace:  KeyNamespace(key),
 		value:      value,
+		createdAt:  time.Now().UnixNano(),
+		staleAt:    staleAt,
 		expiration: expiration,
+		size:       estimateSize(key, value),
 	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, found := c.items[key]; found {
+		c.remove(old)
+	}
+
+	ns := c.namespace(item.namespace)
+	item.global = c.lru.PushFront(item)
+	item.local = ns.lru.PushFront(item)
+	c.items[key] = item
+	c.bytes += item.size
+	ns.bytes += item.size
+
+	c.evictNamespace(item.namespace)
+	c.evictGlobal()
 }
 
-// Get 获取缓存项
+// Get 获取缓存项，过期 (stale) 但未失效的数据同样返回
 func (c *Cache) Get(key string) (interface{}, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	value, _, found := c.Lookup(key)
+	return value, found
+}
+
+// Lookup 获取缓存项，stale 表示数据已超过软过期时间
+func (c *Cache) Lookup(key string) (value interface{}, stale bool, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	item, found := c.items[key]
 	if !found {
-		return nil, false
+		metrics.ObserveCache("memory", KeyNamespace(key), false)
+		return nil, false, false
 	}
 
-	if item.expiration > 0 && time.Now().UnixNano() > item.expiration {
+	now := time.Now().UnixNano()
+	if item.expiration > 0 && now > item.expiration {
 		// 过期但不立即删除，由清理协程处理
-		return nil, false
+		metrics.ObserveCache("memory", item.namespace, false)
+		return nil, false, false
+	}
+
+	c.lru.MoveToFront(item.global)
+	c.namespaces[item.namespace].lru.MoveToFront(item.local)
+
+	stale = item.staleAt > 0 && now > item.staleAt
+	if stale {
+		metrics.ObserveCacheResult("memory", item.namespace, "stale")
+	} else {
+		metrics.ObserveCache("memory", item.namespace, true)
+	}
+	return item.value, stale, true
+}
+
+// IsStale 缓存项存在且已超过软过期时间
+func (c *Cache) IsStale(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, found := c.items[key]
+	if !found {
+		return false
+	}
+	now := time.Now().UnixNano()
+	if item.expiration > 0 && now > item.expiration {
+		return false
+	}
+	return item.staleAt > 0 && now > item.staleAt
+}
+
+// Delete 删除缓存项
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if item, found := c.items[key]; found {
+		c.remove(item)
+	}
+}
+
+// DeletePrefix 删除所有以 prefix 开头的缓存项，返回删除的数量
+func (c *Cache) DeletePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for key, item := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(item)
+			n++
+		}
+	}
+	return n
+}
+
+// Flush 清空缓存，返回删除的数量
+func (c *Cache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.items)
+	for _, item := range c.items {
+		c.remove(item)
 	}
+	return n
+}
+
+// Keys 返回以 prefix 开头且未失效的缓存项信息，按 key 排序
+func (c *Cache) Keys(prefix string) []EntryInfo {
+	now := time.Now().UnixNano()
+	c.mu.Lock()
+	list := make([]EntryInfo, 0)
+	for key, item := range c.items {
+		if strings.HasPrefix(key, prefix) && (item.expiration == 0 || now <= item.expiration) {
+			list = append(list, item.info())
+		}
+	}
+	c.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
 
-	return item.value, true
+// Entry 返回缓存项信息和值，不影响 LRU 顺序
+func (c *Cache) Entry(key string) (EntryInfo, interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, found := c.items[key]
+	if !found || (item.expiration > 0 && time.Now().UnixNano() > item.expiration) {
+		return EntryInfo{}, nil, false
+	}
+	return item.info(), item.value, true
+}
+
+func (item *cacheItem) info() EntryInfo {
+	info := EntryInfo{
+		Key:       item.key,
+		Namespace: item.namespace,
+		Size:      item.size,
+		CreatedAt: time.Unix(0, item.createdAt),
+	}
+	if item.staleAt > 0 {
+		info.StaleAt = time.Unix(0, item.staleAt)
+	}
+	if item.expiration > 0 {
+		info.ExpiresAt = time.Unix(0, item.expiration)
+	}
+	return info
+}
+
+// Len 返回当前缓存项数量 (包含尚未被清理的过期项)
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Stats 返回缓存统计
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := CacheStats{
+		Entries:    len(c.items),
+		Bytes:      c.bytes,
+		Evictions:  c.evictions,
+		Limit:      c.limits.CacheLimit,
+		Namespaces: make(map[string]NamespaceStats, len(c.namespaces)),
+	}
+	for name, ns := range c.namespaces {
+		stats.Namespaces[name] = NamespaceStats{
+			Entries:   ns.lru.Len(),
+			Bytes:     ns.bytes,
+			Evictions: ns.evictions,
+			Limit:     c.limits.Namespaces[name],
+		}
+	}
+	// 配置了上限但还没有数据的命名空间也展示出来
+	for name, limit := range c.limits.Namespaces {
+		if _, found := stats.Namespaces[name]; !found {
+			stats.Namespaces[name] = NamespaceStats{Limit: limit}
+		}
+	}
+	return stats
+}
+
+// namespace 获取命名空间状态，不存在时创建，调用方需持有 c.mu
+func (c *Cache) namespace(name string) *namespaceState {
+	ns, found := c.namespaces[name]
+	if !found {
+		ns = &namespaceState{lru: list.New()}
+		c.namespaces[name] = ns
+	}
+	return ns
+}
+
+// remove 删除缓存项，调用方需持有 c.mu
+func (c *Cache) remove(item *cacheItem) {
+	ns := c.namespaces[item.namespace]
+	c.lru.Remove(item.global)
+	ns.lru.Remove(item.local)
+	delete(c.items, item.key)
+	c.bytes -= item.size
+	ns.bytes -= item.size
+}
+
+// evict 淘汰缓存项，调用方需持有 c.mu
+func (c *Cache) evict(item *cacheItem) {
+	c.remove(item)
+	c.evictions++
+	c.namespaces[item.namespace].evictions++
+	metrics.CacheEvictionsTotal.WithLabelValues(item.namespace).Inc()
+}
+
+// evictNamespace 命名空间超过上限时淘汰其中最久未使用的项
+func (c *Cache) evictNamespace(name string) {
+	limit, found := c.limits.Namespaces[name]
+	ns := c.namespaces[name]
+	if !found || ns == nil {
+		return
+	}
+	for ns.lru.Len() > 0 && exceeds(limit, ns.lru.Len(), ns.bytes) {
+		c.evict(ns.lru.Back().Value.(*cacheItem))
+	}
+}
+
+// evictGlobal 整体超过上限时淘汰最久未使用的项
+func (c *Cache) evictGlobal() {
+	for c.lru.Len() > 0 && exceeds(c.limits.CacheLimit, c.lru.Len(), c.bytes) {
+		c.evict(c.lru.Back().Value.(*cacheItem))
+	}
+}
+
+func exceeds(limit CacheLimit, entries int, bytes int64) bool {
+	return (limit.MaxEntries > 0 && entries > limit.MaxEntries) ||
+		(limit.MaxBytes > 0 && bytes > limit.MaxBytes)
+}
+
+// estimateSize 估算缓存项占用的内存，按 JSON 序列化后的长度计算
+func estimateSize(key string, value interface{}) int64 {
+	size := int64(len(key) + itemOverhead)
+	if data, err := json.Marshal(value); err == nil {
+		size += int64(len(data))
+	}
+	return size
 }
 
 // cleanupLoop 定期清理过期缓存
@@ -83,9 +399,9 @@ func (c *Cache) cleanup() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	for k, v := range c.items {
-		if v.expiration > 0 && now > v.expiration {
-			delete(c.items, k)
+	for _, item := range c.items {
+		if item.expiration > 0 && now > item.expiration {
+			c.remove(item)
 		}
 	}
 }
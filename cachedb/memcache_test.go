@@ -0,0 +1,103 @@
+package cachedb
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheLRUEviction(t *testing.T) {
+	c := NewCache(time.Hour, 0)
+	c.SetLimits(CacheLimits{CacheLimit: CacheLimit{MaxEntries: 3}})
+
+	c.Set("movie:A", "a", 0)
+	c.Set("movie:B", "b", 0)
+	c.Set("movie:C", "c", 0)
+	// 访问 A 后 B 成为最久未使用的项
+	if _, found := c.Get("movie:A"); !found {
+		t.Fatal("movie:A should be cached")
+	}
+	c.Set("movie:D", "d", 0)
+
+	if _, found := c.Get("movie:B"); found {
+		t.Error("movie:B should be evicted")
+	}
+	for _, key := range []string{"movie:A", "movie:C", "movie:D"} {
+		if _, found := c.Get(key); !found {
+			t.Errorf("%s should be cached", key)
+		}
+	}
+	stats := c.Stats()
+	if stats.Entries != 3 || stats.Evictions != 1 || stats.Namespaces["movie"].Evictions != 1 {
+		t.Errorf("Stats() = %+v", stats)
+	}
+
+	// 覆盖写入不会重复计数
+	c.Set("movie:A", "a2", 0)
+	if c.Len() != 3 || c.Stats().Evictions != 1 {
+		t.Errorf("overwrite: len = %d, stats = %+v", c.Len(), c.Stats())
+	}
+}
+
+func TestCacheNamespaceLimits(t *testing.T) {
+	c := NewCache(time.Hour, 0)
+	big := strings.Repeat("x", 1000)
+	c.SetLimits(CacheLimits{Namespaces: map[string]CacheLimit{
+		"mag": {MaxBytes: 3000},
+	}})
+
+	c.Set("movie:A", big, 0)
+	for _, key := range []string{"mag:1", "mag:2", "mag:3", "mag:4"} {
+		c.Set(key, big, 0)
+	}
+
+	stats := c.Stats()
+	mag := stats.Namespaces["mag"]
+	if mag.Bytes > 3000 || mag.Evictions == 0 || mag.Entries >= 4 {
+		t.Errorf("mag stats = %+v", mag)
+	}
+	// 其他命名空间不受影响
+	if _, found := c.Get("movie:A"); !found || stats.Namespaces["movie"].Evictions != 0 {
+		t.Errorf("movie namespace should not be evicted: %+v", stats.Namespaces["movie"])
+	}
+	if _, found := c.Get("mag:4"); !found {
+		t.Error("latest mag entry should be kept")
+	}
+
+	// 删除后字节数同步减少
+	before := c.Stats().Bytes
+	c.Delete("movie:A")
+	if after := c.Stats().Bytes; after >= before || c.Stats().Namespaces["movie"].Entries != 0 {
+		t.Errorf("bytes after delete = %d, before = %d", after, before)
+	}
+}
+
+func TestCacheKeysAndDeletePrefix(t *testing.T) {
+	c := NewCache(time.Hour, 0)
+	c.Set("mag:ABP-123:1:0::", "a", 0)
+	c.Set("mag:ABP-123:2:0::", "b", 0)
+	c.Set("mag:ABP-1234:1:0::", "c", 0)
+	c.Set("movie:ABP-123", "d", 0)
+	c.SetWithStale("movie:expired", "e", time.Now().Add(-time.Hour), time.Now().Add(-time.Minute))
+
+	keys := c.Keys("mag:ABP-123:")
+	if len(keys) != 2 || keys[0].Key != "mag:ABP-123:1:0::" || keys[1].Namespace != "mag" {
+		t.Errorf("Keys() = %+v", keys)
+	}
+	if len(c.Keys("movie:")) != 1 {
+		t.Error("expired entries should not be listed")
+	}
+	if info, value, found := c.Entry("movie:ABP-123"); !found || value != "d" || info.CreatedAt.IsZero() || !info.ExpiresAt.After(time.Now()) {
+		t.Errorf("Entry() = %+v, %v, %v", info, value, found)
+	}
+
+	if n := c.DeletePrefix("mag:ABP-123:"); n != 2 {
+		t.Errorf("DeletePrefix() = %d, want 2", n)
+	}
+	if _, found := c.Get("mag:ABP-1234:1:0::"); !found {
+		t.Error("mag:ABP-1234 should not be deleted")
+	}
+	if n := c.Flush(); n != 3 || c.Len() != 0 || c.Stats().Bytes != 0 {
+		t.Errorf("Flush() = %d, len = %d, stats = %+v", n, c.Len(), c.Stats())
+	}
+}
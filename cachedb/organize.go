@@ -0,0 +1,107 @@
+package cachedb
+
+import (
+	"errors"
+	"time"
+)
+
+// 整理日志状态
+const (
+	JournalStatusMoved    = "moved"
+	JournalStatusReverted = "reverted"
+	JournalStatusFailed   = "failed"
+)
+
+// OrganizeJournal 文件整理日志，每移动一个文件 (视频或字幕) 记录一行，用于撤销
+type OrganizeJournal struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	BatchID       string     `gorm:"size:64;index;not null" json:"batchId"`
+	LibraryItemID uint       `gorm:"index" json:"libraryItemId"`
+	Kind          string     `gorm:"size:16" json:"kind"` // video / subtitle
+	Source        string     `gorm:"not null" json:"source"`
+	Target        string     `gorm:"not null" json:"target"`
+	Backup        string     `json:"backup,omitempty"` // overwrite 策略下被替换的原目标文件的备份位置，撤销时恢复
+	Status        string     `gorm:"size:16;index" json:"status"`
+	Error         string     `json:"error"`
+	RevertedAt    *time.Time `json:"revertedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+// OrganizeBatch 一次整理操作的汇总
+type OrganizeBatch struct {
+	BatchID   string    `json:"batchId"`
+	Files     int64     `json:"files"`
+	Reverted  int64     `json:"reverted"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SaveJournal 写入整理日志
+func SaveJournal(entry *OrganizeJournal) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Save(entry).Error
+}
+
+// ListJournal 查询某次整理的全部日志，按写入顺序返回
+func ListJournal(batchID string) ([]OrganizeJournal, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	entries := []OrganizeJournal{}
+	err := CacheDb.Where("batch_id = ?", batchID).Order("id").Find(&entries).Error
+	return entries, err
+}
+
+// ListOrganizeBatches 按时间倒序列出最近的整理批次
+func ListOrganizeBatches(limit int) ([]OrganizeBatch, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// 聚合结果中的时间在 sqlite 下会变成字符串，这里只聚合 id，时间再单独查询
+	var rows []struct {
+		BatchID  string
+		Files    int64
+		Reverted int64
+		FirstID  uint
+	}
+	err := CacheDb.Model(&OrganizeJournal{}).
+		Select("batch_id, COUNT(*) AS files, SUM(CASE WHEN status = ? THEN 1 ELSE 0 END) AS reverted, MIN(id) AS first_id", JournalStatusReverted).
+		Group("batch_id").
+		Order("first_id DESC").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.FirstID)
+	}
+	var firsts []OrganizeJournal
+	if len(ids) > 0 {
+		if err := CacheDb.Select("id, created_at").Where("id IN ?", ids).Find(&firsts).Error; err != nil {
+			return nil, err
+		}
+	}
+	createdAt := make(map[uint]time.Time, len(firsts))
+	for _, f := range firsts {
+		createdAt[f.ID] = f.CreatedAt
+	}
+
+	batches := make([]OrganizeBatch, 0, len(rows))
+	for _, row := range rows {
+		batches = append(batches, OrganizeBatch{
+			BatchID:   row.BatchID,
+			Files:     row.Files,
+			Reverted:  row.Reverted,
+			CreatedAt: createdAt[row.FirstID],
+		})
+	}
+	return batches, nil
+}
@@ -0,0 +1,191 @@
+package cachedb
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/fireinrain/javbus-api/metrics"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScrapeCache 持久化的爬取结果缓存
+// 影片详情、演员信息、磁力列表、影片列表都以 JSON 的形式存放在这张表中，
+// CacheKey 与内存缓存的 key 保持一致 (movie:xxx / star:xxx / mag:xxx / movies:xxx / search:xxx)，多个副本指向同一个数据库时可以共享
+type ScrapeCache struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CacheKey  string    `gorm:"size:255;uniqueIndex;not null" json:"cacheKey"`
+	Namespace string    `gorm:"size:32;index" json:"namespace"`
+	Data      string    `json:"-"`
+	StaleAt   time.Time `json:"staleAt"` // 软过期时间，之后的数据仍可返回但需要后台刷新
+	ExpiresAt time.Time `gorm:"index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// KeyNamespace 取缓存 key 的命名空间 (第一个冒号之前的部分)
+func KeyNamespace(key string) string {
+	if idx := strings.Index(key, ":"); idx > 0 {
+		return key[:idx]
+	}
+	return key
+}
+
+// LoadPersisted 从数据库读取未过期的缓存并反序列化到 out
+// 返回缓存剩余的有效期，数据库未初始化、未命中或已过期时返回 false
+func LoadPersisted(key string, out interface{}) (time.Duration, bool) {
+	row, found := LoadPersistedEntry(key, out)
+	if !found {
+		return 0, false
+	}
+	return time.Until(row.ExpiresAt), true
+}
+
+// LoadPersistedEntry 与 LoadPersisted 相同，返回整行记录 (不含 Data) 以便读取软过期时间
+// 旧数据没有软过期时间时视为与 ExpiresAt 相同
+func LoadPersistedEntry(key string, out interface{}) (*ScrapeCache, bool) {
+	if CacheDb == nil {
+		return nil, false
+	}
+
+	var row ScrapeCache
+	err := CacheDb.Where("cache_key = ?", key).Take(&row).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("读取持久化缓存失败 %s: %v", key, err)
+		}
+		metrics.ObserveCache("database", KeyNamespace(key), false)
+		return nil, false
+	}
+
+	now := time.Now()
+	if !row.ExpiresAt.After(now) {
+		// 过期数据留给 PurgeExpiredCache 统一清理
+		metrics.ObserveCache("database", KeyNamespace(key), false)
+		return nil, false
+	}
+
+	if err := json.Unmarshal([]byte(row.Data), out); err != nil {
+		log.Printf("解析持久化缓存失败 %s: %v", key, err)
+		metrics.ObserveCache("database", KeyNamespace(key), false)
+		return nil, false
+	}
+	row.Data = ""
+	if row.StaleAt.IsZero() || row.StaleAt.After(row.ExpiresAt) {
+		row.StaleAt = row.ExpiresAt
+	}
+	if row.StaleAt.Before(now) {
+		metrics.ObserveCacheResult("database", KeyNamespace(key), "stale")
+	} else {
+		metrics.ObserveCache("database", KeyNamespace(key), true)
+	}
+	return &row, true
+}
+
+// SavePersisted 将缓存写入数据库，已存在的 key 会被覆盖
+func SavePersisted(key string, value interface{}, ttl time.Duration) error {
+	return SavePersistedStale(key, value, ttl, ttl)
+}
+
+// SavePersistedStale 将缓存写入数据库，staleTTL 后数据变为过期 (仍可读取)，ttl 后不可读取
+func SavePersistedStale(key string, value interface{}, staleTTL, ttl time.Duration) error {
+	if CacheDb == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	row := ScrapeCache{
+		CacheKey:  key,
+		Namespace: KeyNamespace(key),
+		Data:      string(data),
+		StaleAt:   now.Add(staleTTL),
+		ExpiresAt: now.Add(ttl),
+	}
+	return CacheDb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "cache_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"namespace", "data", "stale_at", "expires_at", "updated_at"}),
+	}).Create(&row).Error
+}
+
+// DeletePersisted 删除指定 key 的持久化缓存
+func DeletePersisted(key string) error {
+	if CacheDb == nil {
+		return nil
+	}
+	return CacheDb.Where("cache_key = ?", key).Delete(&ScrapeCache{}).Error
+}
+
+// likeEscaper 转义 LIKE 中的通配符，使用 ! 作为转义字符以兼容 sqlite / mysql / postgres
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// prefixCondition key 以 prefix 开头的查询条件
+func prefixCondition(db *gorm.DB, prefix string) *gorm.DB {
+	if prefix == "" {
+		return db
+	}
+	return db.Where("cache_key LIKE ? ESCAPE '!'", likeEscaper.Replace(prefix)+"%")
+}
+
+// ListPersisted 列出以 prefix 开头且未过期的缓存 (不含 Data)，按 key 排序
+func ListPersisted(prefix string, limit int) ([]ScrapeCache, error) {
+	if CacheDb == nil {
+		return nil, nil
+	}
+	var rows []ScrapeCache
+	err := prefixCondition(CacheDb.Omit("data"), prefix).
+		Where("expires_at > ?", time.Now()).
+		Order("cache_key").
+		Limit(limit).
+		Find(&rows).Error
+	return rows, err
+}
+
+// GetPersisted 读取一条缓存 (包括已过期未清理的)，不存在时返回 nil
+func GetPersisted(key string) (*ScrapeCache, error) {
+	if CacheDb == nil {
+		return nil, nil
+	}
+	var row ScrapeCache
+	err := CacheDb.Where("cache_key = ?", key).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if row.StaleAt.IsZero() {
+		row.StaleAt = row.ExpiresAt
+	}
+	return &row, nil
+}
+
+// DeletePersistedPrefix 删除以 prefix 开头的持久化缓存，prefix 为空时删除全部
+func DeletePersistedPrefix(prefix string) (int64, error) {
+	if CacheDb == nil {
+		return 0, nil
+	}
+	db := CacheDb
+	if prefix == "" {
+		// 不带条件的删除会被 gorm 拒绝
+		db = db.Where("1 = 1")
+	}
+	result := prefixCondition(db, prefix).Delete(&ScrapeCache{})
+	return result.RowsAffected, result.Error
+}
+
+// PurgeExpiredCache 清理数据库中所有已过期的缓存，返回删除的行数
+func PurgeExpiredCache() (int64, error) {
+	if CacheDb == nil {
+		return 0, nil
+	}
+	result := CacheDb.Where("expires_at < ?", time.Now()).Delete(&ScrapeCache{})
+	return result.RowsAffected, result.Error
+}
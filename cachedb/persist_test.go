@@ -0,0 +1,83 @@
+package cachedb
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fireinrain/javbus-api/config"
+)
+
+func TestPersistedCache(t *testing.T) {
+	db, err := InitDataBase(config.DatabaseConfig{
+		DBType:       "sqlite",
+		DBServerPath: filepath.Join(t.TempDir(), "cache.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	CacheDb = db
+	defer func() { CacheDb = nil }()
+
+	type payload struct {
+		Title string `json:"title"`
+	}
+
+	if err := SavePersisted("movie:ABP-123", payload{Title: "first"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	// 同一个 key 再次写入应覆盖而不是报唯一索引冲突
+	if err := SavePersisted("movie:ABP-123", payload{Title: "second"}, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	var got payload
+	remaining, found := LoadPersisted("movie:ABP-123", &got)
+	if !found || got.Title != "second" {
+		t.Fatalf("LoadPersisted() = %+v, %v; want second, true", got, found)
+	}
+	if remaining <= 0 || remaining > time.Hour {
+		t.Errorf("remaining ttl = %v, want (0, 1h]", remaining)
+	}
+
+	// 超过软过期时间的数据仍可读取，StaleAt 表示何时开始过期
+	if err := SavePersistedStale("star::okq", payload{Title: "stale"}, -time.Minute, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	row, found := LoadPersistedEntry("star::okq", &got)
+	if !found || got.Title != "stale" || !row.StaleAt.Before(time.Now()) || !row.ExpiresAt.After(time.Now()) {
+		t.Errorf("LoadPersistedEntry() = %+v, %+v, %v", got, row, found)
+	}
+
+	// 已过期的缓存不应被读取，并能被清理掉
+	if err := SavePersisted("mag:ABP-123:1:0::", payload{Title: "old"}, -time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, found := LoadPersisted("mag:ABP-123:1:0::", &got); found {
+		t.Error("expired entry should not be loaded")
+	}
+	purged, err := PurgeExpiredCache()
+	if err != nil || purged != 1 {
+		t.Errorf("PurgeExpiredCache() = %d, %v; want 1, nil", purged, err)
+	}
+
+	// 按前缀列出和删除，前缀中的 _ % 不作为通配符
+	for _, key := range []string{"mag:ABP-123:a", "mag:ABP-123:b", "mag:ABP-1234:a", "mag:ABP_123:a"} {
+		if err := SavePersisted(key, payload{Title: key}, time.Hour); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rows, err := ListPersisted("mag:ABP-123:", 10)
+	if err != nil || len(rows) != 2 || rows[0].CacheKey != "mag:ABP-123:a" || rows[0].Data != "" {
+		t.Errorf("ListPersisted() = %+v, %v", rows, err)
+	}
+	if row, err := GetPersisted("mag:ABP_123:a"); err != nil || row == nil || row.Data == "" {
+		t.Errorf("GetPersisted() = %+v, %v", row, err)
+	}
+	if deleted, err := DeletePersistedPrefix("mag:ABP_"); err != nil || deleted != 1 {
+		t.Errorf("DeletePersistedPrefix() = %d, %v; want 1, nil", deleted, err)
+	}
+	if deleted, err := DeletePersistedPrefix(""); err != nil || deleted != 5 {
+		t.Errorf("DeletePersistedPrefix(\"\") = %d, %v; want 5, nil", deleted, err)
+	}
+}
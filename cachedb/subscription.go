@@ -0,0 +1,216 @@
+package cachedb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Subscription 新片订阅，按 FilterType/FilterValue (如 star/okq) 定期检查列表页
+type Subscription struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	Name        string `json:"name"`
+	FilterType  string `gorm:"size:16;uniqueIndex:idx_subscription_filter;not null" json:"filterType"`
+	FilterValue string `gorm:"size:128;uniqueIndex:idx_subscription_filter;not null" json:"filterValue"`
+	Type        string `gorm:"size:16;uniqueIndex:idx_subscription_filter" json:"type"`   // normal / uncensored
+	Magnet      string `gorm:"size:16;uniqueIndex:idx_subscription_filter" json:"magnet"` // all / exist
+	// Initialized 首次检查完成后为 true，首次检查时已存在的影片只作为基线，不算新片
+	Initialized   bool       `json:"initialized"`
+	LastCheckedAt *time.Time `json:"lastCheckedAt"`
+	LastNewAt     *time.Time `json:"lastNewAt"`
+	LastError     string     `json:"lastError"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+
+	// NewCount 未读新片数量，查询时填充
+	NewCount int64 `gorm:"-" json:"newCount"`
+}
+
+// SubscriptionMovie 订阅中出现过的影片
+type SubscriptionMovie struct {
+	ID             uint     `gorm:"primaryKey" json:"-"`
+	SubscriptionID uint     `gorm:"uniqueIndex:idx_subscription_movie;not null" json:"subscriptionId"`
+	MovieID        string   `gorm:"size:64;uniqueIndex:idx_subscription_movie;not null" json:"id"`
+	Title          string   `json:"title"`
+	Img            string   `json:"img"`
+	Date           string   `gorm:"size:16" json:"date"`
+	Tags           []string `gorm:"serializer:json" json:"tags"`
+	// Baseline 首次检查时已存在的影片
+	Baseline bool `gorm:"index" json:"baseline"`
+	// Acked 新片已被确认 (已读)
+	Acked   bool      `gorm:"index" json:"acked"`
+	FoundAt time.Time `json:"foundAt"`
+}
+
+// CreateSubscription 新建订阅
+func CreateSubscription(sub *Subscription) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Create(sub).Error
+}
+
+// SaveSubscription 更新订阅
+func SaveSubscription(sub *Subscription) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Save(sub).Error
+}
+
+// GetSubscription 按 ID 查询订阅，不存在时返回 nil
+func GetSubscription(id uint) (*Subscription, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var sub Subscription
+	err := CacheDb.Take(&sub, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := fillNewCount([]*Subscription{&sub}); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// FindSubscription 按过滤条件查询订阅，不存在时返回 nil
+func FindSubscription(filterType, filterValue, movieType, magnet string) (*Subscription, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var sub Subscription
+	err := CacheDb.Where("filter_type = ? AND filter_value = ? AND type = ? AND magnet = ?",
+		filterType, filterValue, movieType, magnet).Take(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions 列出全部订阅
+func ListSubscriptions() ([]Subscription, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	subs := []Subscription{}
+	if err := CacheDb.Order("id").Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	ptrs := make([]*Subscription, len(subs))
+	for i := range subs {
+		ptrs[i] = &subs[i]
+	}
+	return subs, fillNewCount(ptrs)
+}
+
+// DeleteSubscription 删除订阅及其影片记录
+func DeleteSubscription(id uint) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&SubscriptionMovie{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Subscription{}, id).Error
+	})
+}
+
+// KnownSubscriptionMovieIDs 返回 ids 中已经记录过的影片
+func KnownSubscriptionMovieIDs(subID uint, ids []string) (map[string]bool, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	known := make(map[string]bool, len(ids))
+	if len(ids) == 0 {
+		return known, nil
+	}
+	var found []string
+	err := CacheDb.Model(&SubscriptionMovie{}).
+		Where("subscription_id = ? AND movie_id IN ?", subID, ids).
+		Pluck("movie_id", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range found {
+		known[id] = true
+	}
+	return known, nil
+}
+
+// AddSubscriptionMovies 记录订阅中新出现的影片，已存在的忽略
+func AddSubscriptionMovies(movies []SubscriptionMovie) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	if len(movies) == 0 {
+		return nil
+	}
+	return CacheDb.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(movies, 100).Error
+}
+
+// ListNewSubscriptionMovies 列出未读的新片，按发现时间倒序
+func ListNewSubscriptionMovies(subID uint) ([]SubscriptionMovie, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	movies := []SubscriptionMovie{}
+	err := CacheDb.Where("subscription_id = ? AND baseline = ? AND acked = ?", subID, false, false).
+		Order("found_at DESC, id DESC").
+		Find(&movies).Error
+	return movies, err
+}
+
+// AckSubscriptionMovies 把新片标记为已读，movieIDs 为空时标记全部
+func AckSubscriptionMovies(subID uint, movieIDs []string) (int64, error) {
+	if CacheDb == nil {
+		return 0, errors.New("database not initialized")
+	}
+	tx := CacheDb.Model(&SubscriptionMovie{}).
+		Where("subscription_id = ? AND baseline = ? AND acked = ?", subID, false, false)
+	if len(movieIDs) > 0 {
+		tx = tx.Where("movie_id IN ?", movieIDs)
+	}
+	result := tx.Update("acked", true)
+	return result.RowsAffected, result.Error
+}
+
+// fillNewCount 填充订阅的未读新片数量
+func fillNewCount(subs []*Subscription) error {
+	if len(subs) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(subs))
+	for i, s := range subs {
+		ids[i] = s.ID
+	}
+	var rows []struct {
+		SubscriptionID uint
+		Count          int64
+	}
+	err := CacheDb.Model(&SubscriptionMovie{}).
+		Select("subscription_id, COUNT(*) AS count").
+		Where("subscription_id IN ? AND baseline = ? AND acked = ?", ids, false, false).
+		Group("subscription_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.SubscriptionID] = row.Count
+	}
+	for _, s := range subs {
+		s.NewCount = counts[s.ID]
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cachedb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 磁力监控的通知条件
+const (
+	WatchNotifyAny      = "any"      // 出现任意磁力链接
+	WatchNotifyHD       = "hd"       // 出现高清磁力链接
+	WatchNotifySubtitle = "subtitle" // 出现字幕磁力链接
+)
+
+// MagnetWatch 磁力监控: 影片满足条件 (出现磁力 / 高清 / 字幕) 时发送通知
+type MagnetWatch struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	MovieID  string `gorm:"size:64;uniqueIndex:idx_magnet_watch;not null" json:"movieId"`
+	NotifyOn string `gorm:"size:16;uniqueIndex:idx_magnet_watch;not null" json:"notifyOn"`
+	Title    string `json:"title"`
+	Img      string `json:"img"`
+	Date     string `gorm:"size:16" json:"date"`
+	GID      string `gorm:"size:32" json:"gid"`
+	UC       string `gorm:"size:8" json:"uc"`
+
+	MagnetCount int  `json:"magnetCount"`
+	HasHD       bool `json:"hasHD"`
+	HasSubtitle bool `json:"hasSubtitle"`
+	// Fulfilled 条件已满足 (已通知或创建时就已满足)，之后不再检查
+	Fulfilled bool `gorm:"index" json:"fulfilled"`
+
+	LastCheckedAt *time.Time `json:"lastCheckedAt"`
+	NotifiedAt    *time.Time `json:"notifiedAt"`
+	LastError     string     `json:"lastError"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+}
+
+// SaveMagnetWatch 新增或更新磁力监控
+func SaveMagnetWatch(w *MagnetWatch) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Save(w).Error
+}
+
+// GetMagnetWatch 按 ID 查询，不存在时返回 nil
+func GetMagnetWatch(id uint) (*MagnetWatch, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var w MagnetWatch
+	err := CacheDb.Take(&w, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// FindMagnetWatch 按影片和通知条件查询，不存在时返回 nil
+func FindMagnetWatch(movieID, notifyOn string) (*MagnetWatch, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var w MagnetWatch
+	err := CacheDb.Where("movie_id = ? AND notify_on = ?", movieID, notifyOn).Take(&w).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// ListMagnetWatches 列出磁力监控，fulfilled 为 nil 时返回全部
+func ListMagnetWatches(fulfilled *bool) ([]MagnetWatch, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	tx := CacheDb.Order("id")
+	if fulfilled != nil {
+		tx = tx.Where("fulfilled = ?", *fulfilled)
+	}
+	watches := []MagnetWatch{}
+	err := tx.Find(&watches).Error
+	return watches, err
+}
+
+// DeleteMagnetWatch 删除磁力监控
+func DeleteMagnetWatch(id uint) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Delete(&MagnetWatch{}, id).Error
+}
@@ -0,0 +1,107 @@
+package cachedb
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook 投递状态
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSuccess = "success"
+	DeliveryStatusFailed  = "failed"
+)
+
+// WebhookDelivery webhook 投递记录，每个事件对每个地址一行
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	EventID        string     `gorm:"size:64;index;not null" json:"eventId"`
+	EventType      string     `gorm:"size:64;index" json:"eventType"`
+	URL            string     `gorm:"not null" json:"url"`
+	Payload        string     `gorm:"type:text" json:"payload"`
+	Status         string     `gorm:"size:16;index" json:"status"`
+	Attempts       int        `json:"attempts"`
+	LastStatusCode int        `json:"lastStatusCode"`
+	LastError      string     `json:"lastError"`
+	NextRetryAt    *time.Time `json:"nextRetryAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+}
+
+// DeliveryQuery 投递记录查询条件
+type DeliveryQuery struct {
+	Status    string
+	EventType string
+	Page      int
+	PageSize  int
+}
+
+// SaveDelivery 新增或更新投递记录
+func SaveDelivery(d *WebhookDelivery) error {
+	if CacheDb == nil {
+		return errors.New("database not initialized")
+	}
+	return CacheDb.Save(d).Error
+}
+
+// GetDelivery 按 ID 查询投递记录，不存在时返回 nil
+func GetDelivery(id uint) (*WebhookDelivery, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	var d WebhookDelivery
+	err := CacheDb.Take(&d, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// ListDeliveries 分页查询投递记录，按时间倒序
+func ListDeliveries(q DeliveryQuery) ([]WebhookDelivery, int64, error) {
+	if CacheDb == nil {
+		return nil, 0, errors.New("database not initialized")
+	}
+	if q.Page < 1 {
+		q.Page = 1
+	}
+	if q.PageSize < 1 || q.PageSize > 200 {
+		q.PageSize = 50
+	}
+
+	tx := CacheDb.Model(&WebhookDelivery{})
+	if q.Status != "" {
+		tx = tx.Where("status = ?", q.Status)
+	}
+	if q.EventType != "" {
+		tx = tx.Where("event_type = ?", q.EventType)
+	}
+
+	var total int64
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	deliveries := []WebhookDelivery{}
+	err := tx.Order("id DESC").
+		Offset((q.Page - 1) * q.PageSize).
+		Limit(q.PageSize).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+// ListPendingDeliveries 列出尚未完成的投递 (用于重启后继续重试)
+func ListPendingDeliveries() ([]WebhookDelivery, error) {
+	if CacheDb == nil {
+		return nil, errors.New("database not initialized")
+	}
+	deliveries := []WebhookDelivery{}
+	err := CacheDb.Where("status = ?", DeliveryStatusPending).Order("id").Find(&deliveries).Error
+	return deliveries, err
+}
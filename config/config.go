@@ -6,6 +6,7 @@ import (
 	"os"
 	"regexp"
 
+	"github.com/fireinrain/javbus-api/consts"
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
 )
@@ -20,9 +21,41 @@ type ServerConfig struct {
 }
 
 type ProxyConfig struct {
-	HttpProxy   string `mapstructure:"http_proxy"`
-	HttpsProxy  string `mapstructure:"https_proxy"`
-	Socks5Proxy string `mapstructure:"socks5_proxy"`
+	HttpProxy           string   `mapstructure:"http_proxy"`
+	HttpsProxy          string   `mapstructure:"https_proxy"`
+	Socks5Proxy         string   `mapstructure:"socks5_proxy"`
+	Proxies             []string `mapstructure:"proxies"`
+	MaxFailures         int      `mapstructure:"max_failures"`          // 连续失败多少次后标记为不可用
+	HealthCheckInterval int      `mapstructure:"health_check_interval"` // 不可用代理的检查间隔 (秒)
+	HealthCheckURL      string   `mapstructure:"health_check_url"`
+}
+
+type JavbusConfig struct {
+	BaseURL string   `mapstructure:"base_url"` // 主域名，返回的图片链接统一使用该域名
+	Mirrors []string `mapstructure:"mirrors"`  // 备用镜像域名，主域名不可用时依次切换
+}
+
+type CacheLimitConfig struct {
+	MaxEntries  int `mapstructure:"max_entries"`   // 最大条目数，0 表示不限制
+	MaxMemoryMB int `mapstructure:"max_memory_mb"` // 估算内存上限 (MB)，0 表示不限制
+}
+
+type CacheConfig struct {
+	CacheLimitConfig `mapstructure:",squash"`
+	// 按缓存 key 的命名空间单独限制: movie / star / mag / movies / search
+	Namespaces map[string]CacheLimitConfig `mapstructure:"namespaces"`
+}
+
+type RateLimitConfig struct {
+	RequestsPerSecond        float64 `mapstructure:"requests_per_second"`          // 全局每秒请求数，0 表示不限制
+	Burst                    int     `mapstructure:"burst"`                        // 全局允许的突发请求数
+	PerHostRequestsPerSecond float64 `mapstructure:"per_host_requests_per_second"` // 每个域名每秒请求数，0 表示不限制
+	PerHostBurst             int     `mapstructure:"per_host_burst"`
+	MaxConcurrent            int     `mapstructure:"max_concurrent"` // 同时进行的上游请求数，0 表示不限制
+	JitterMinMs              int     `mapstructure:"jitter_min_ms"`  // 每个请求发出前的随机等待 (毫秒)
+	JitterMaxMs              int     `mapstructure:"jitter_max_ms"`
+	BackoffSeconds           int     `mapstructure:"backoff_seconds"`     // 域名返回 403/429/503 后暂停请求的时间 (秒)，连续出现时翻倍
+	MaxBackoffSeconds        int     `mapstructure:"max_backoff_seconds"` // 暂停时间上限 (秒)，同样限制 Retry-After，0 表示不设上限
 }
 
 type AdminConfig struct {
@@ -42,18 +75,50 @@ type DatabaseConfig struct {
 	MaxIdleConns int    `mapstructure:"max_idle_conns"`
 }
 
+type LibraryConfig struct {
+	Dirs             []string `mapstructure:"dirs"`
+	Extensions       []string `mapstructure:"extensions"`
+	OrganizeDir      string   `mapstructure:"organize_dir"`
+	OrganizeTemplate string   `mapstructure:"organize_template"`
+	OrganizeConflict string   `mapstructure:"organize_conflict"`
+}
+
+type SchedulerConfig struct {
+	Enabled              bool `mapstructure:"enabled"`
+	SubscriptionInterval int  `mapstructure:"subscription_interval"` // 订阅检查间隔 (分钟)
+	SubscriptionPages    int  `mapstructure:"subscription_pages"`    // 每次检查的最大页数
+	CachePurgeInterval   int  `mapstructure:"cache_purge_interval"`  // 过期缓存清理间隔 (分钟)
+	MagnetWatchInterval  int  `mapstructure:"magnet_watch_interval"` // 磁力监控检查间隔 (分钟)
+}
+
+type WebhookConfig struct {
+	URLs       []string `mapstructure:"urls"`
+	Secret     string   `mapstructure:"secret"`
+	MaxRetries int      `mapstructure:"max_retries"`
+	Timeout    int      `mapstructure:"timeout"`  // 单次请求超时 (秒)
+	Receiver   bool     `mapstructure:"receiver"` // 启用本地测试接收端 /webhook/receive
+}
+
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Proxy    ProxyConfig    `mapstructure:"proxy"`
-	Admin    AdminConfig    `mapstructure:"admin"`
-	Auth     AuthConfig     `mapstructure:"auth"`
-	DATABASE DatabaseConfig `mapstructure:"database"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Proxy     ProxyConfig     `mapstructure:"proxy"`
+	Javbus    JavbusConfig    `mapstructure:"javbus"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	RateLimit RateLimitConfig `mapstructure:"ratelimit"`
+	Admin     AdminConfig     `mapstructure:"admin"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	DATABASE  DatabaseConfig  `mapstructure:"database"`
+	Library   LibraryConfig   `mapstructure:"library"`
+	Scheduler SchedulerConfig `mapstructure:"scheduler"`
+	Webhook   WebhookConfig   `mapstructure:"webhook"`
 }
 
 var GlobalConfig *Config
 
 var (
-	proxyRegex = regexp.MustCompile(`^(https?|socks5?):\/\/`)
+	proxyRegex   = regexp.MustCompile(`^(https?|socks5?):\/\/`)
+	webhookRegex = regexp.MustCompile(`^https?://`)
+	siteRegex    = regexp.MustCompile(`^https?://[^/]+/?$`)
 )
 
 // ==========================================
@@ -69,6 +134,49 @@ func InitConfig() (*Config, error) {
 	v.SetDefault("server.debug_level", "debug")
 	v.SetDefault("server.server_port", 3000)
 
+	// 代理池
+	v.SetDefault("proxy.max_failures", 2)
+	v.SetDefault("proxy.health_check_interval", 60)
+
+	// JavBus 域名
+	v.SetDefault("javbus.base_url", consts.JavBusURL)
+
+	// 内存缓存上限
+	v.SetDefault("cache.max_entries", 20000)
+	v.SetDefault("cache.max_memory_mb", 256)
+	v.SetDefault("cache.namespaces.movie.max_memory_mb", 96)
+	v.SetDefault("cache.namespaces.mag.max_memory_mb", 64)
+	v.SetDefault("cache.namespaces.movies.max_memory_mb", 32)
+	v.SetDefault("cache.namespaces.search.max_memory_mb", 32)
+	v.SetDefault("cache.namespaces.star.max_memory_mb", 16)
+
+	// 上游限流
+	v.SetDefault("ratelimit.requests_per_second", 5)
+	v.SetDefault("ratelimit.burst", 10)
+	v.SetDefault("ratelimit.per_host_requests_per_second", 2)
+	v.SetDefault("ratelimit.per_host_burst", 4)
+	v.SetDefault("ratelimit.max_concurrent", 8)
+	v.SetDefault("ratelimit.jitter_min_ms", 50)
+	v.SetDefault("ratelimit.jitter_max_ms", 300)
+	v.SetDefault("ratelimit.backoff_seconds", 30)
+	v.SetDefault("ratelimit.max_backoff_seconds", 600)
+
+	// 本地视频库
+	v.SetDefault("library.extensions", []string{".mp4", ".mkv", ".avi", ".wmv", ".mov", ".ts", ".m2ts", ".flv", ".rmvb", ".iso"})
+	v.SetDefault("library.organize_template", "{studio}/{id} {title}/{id}")
+	v.SetDefault("library.organize_conflict", "skip")
+
+	// 定时任务
+	v.SetDefault("scheduler.enabled", true)
+	v.SetDefault("scheduler.subscription_interval", 60)
+	v.SetDefault("scheduler.subscription_pages", 2)
+	v.SetDefault("scheduler.cache_purge_interval", 360)
+	v.SetDefault("scheduler.magnet_watch_interval", 180)
+
+	// Webhook
+	v.SetDefault("webhook.max_retries", 5)
+	v.SetDefault("webhook.timeout", 10)
+
 	// 使用 TOML
 	v.SetConfigName("config")
 	v.SetConfigType("toml")
@@ -119,5 +227,63 @@ func validate(c *Config) error {
 		return fmt.Errorf("SOCKS5_PROXY 格式错误: 必须以 socks:// 或 socks5:// 开头")
 	}
 
+	for _, p := range c.Proxy.Proxies {
+		if !proxyRegex.MatchString(p) {
+			return fmt.Errorf("PROXIES 格式错误: %s 必须以 http://, https://, socks:// 或 socks5:// 开头", p)
+		}
+	}
+
+	// JavBus 域名只能是 scheme + host
+	if !siteRegex.MatchString(c.Javbus.BaseURL) {
+		return fmt.Errorf("JAVBUS BASE_URL 格式错误: %s 必须形如 https://www.javbus.com", c.Javbus.BaseURL)
+	}
+	for _, m := range c.Javbus.Mirrors {
+		if !siteRegex.MatchString(m) {
+			return fmt.Errorf("JAVBUS MIRRORS 格式错误: %s 必须形如 https://www.javsee.com", m)
+		}
+	}
+
+	// 缓存上限
+	if c.Cache.MaxEntries < 0 || c.Cache.MaxMemoryMB < 0 {
+		return fmt.Errorf("CACHE 上限不能为负数")
+	}
+	for name, limit := range c.Cache.Namespaces {
+		if limit.MaxEntries < 0 || limit.MaxMemoryMB < 0 {
+			return fmt.Errorf("CACHE NAMESPACES %s 上限不能为负数", name)
+		}
+	}
+
+	// 上游限流
+	rl := c.RateLimit
+	if rl.RequestsPerSecond < 0 || rl.Burst < 0 || rl.PerHostRequestsPerSecond < 0 || rl.PerHostBurst < 0 ||
+		rl.MaxConcurrent < 0 || rl.JitterMinMs < 0 || rl.BackoffSeconds < 0 || rl.MaxBackoffSeconds < 0 {
+		return fmt.Errorf("RATELIMIT 配置不能为负数")
+	}
+	if rl.JitterMaxMs < rl.JitterMinMs {
+		return fmt.Errorf("RATELIMIT JITTER_MAX_MS 不能小于 JITTER_MIN_MS")
+	}
+	if rl.MaxBackoffSeconds > 0 && rl.MaxBackoffSeconds < rl.BackoffSeconds {
+		return fmt.Errorf("RATELIMIT MAX_BACKOFF_SECONDS 不能小于 BACKOFF_SECONDS")
+	}
+
+	// 整理冲突策略
+	switch c.Library.OrganizeConflict {
+	case "", "skip", "rename", "overwrite":
+	default:
+		return fmt.Errorf("ORGANIZE_CONFLICT 格式错误: 必须为 skip, rename 或 overwrite")
+	}
+
+	// 定时任务
+	if c.Scheduler.SubscriptionInterval < 0 || c.Scheduler.CachePurgeInterval < 0 || c.Scheduler.MagnetWatchInterval < 0 {
+		return fmt.Errorf("SCHEDULER 间隔不能为负数")
+	}
+
+	// Webhook 地址检查
+	for _, u := range c.Webhook.URLs {
+		if !webhookRegex.MatchString(u) {
+			return fmt.Errorf("WEBHOOK URLS 格式错误: %s 必须以 http:// 或 https:// 开头", u)
+		}
+	}
+
 	return nil
 }
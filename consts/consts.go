@@ -16,6 +16,40 @@ const (
 	UserAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.114 Safari/537.36"
 	// CacheExpire 缓存过期时间
 	CacheExpire = 3 * time.Hour
+	// PersistCacheExpire 数据库持久化缓存过期时间 (影片详情、演员信息)
+	PersistCacheExpire = 7 * 24 * time.Hour
+	// MagnetPersistExpire 磁力列表会随时间增加，持久化时间相对短一些
+	MagnetPersistExpire = 12 * time.Hour
+	// PersistStaleExpire 影片详情、演员信息超过 PersistCacheExpire 后仍可作为过期数据返回的最长时间 (上游不可用时兜底)
+	PersistStaleExpire = 30 * 24 * time.Hour
+	// MagnetStaleExpire 磁力列表作为过期数据返回的最长时间
+	MagnetStaleExpire = 7 * 24 * time.Hour
+	// ListCacheExpire 影片列表、搜索结果变化较快，软过期时间比详情短
+	ListCacheExpire = 15 * time.Minute
+	// ListStaleExpire 影片列表、搜索结果作为过期数据返回的最长时间
+	ListStaleExpire = 24 * time.Hour
+	// StarMoviesMaxPages 抓取演员全部作品时最多翻几页 (每页 30 部)
+	StarMoviesMaxPages = 50
+	// CrawlDefaultPages 多页抓取默认最多抓取的页数
+	CrawlDefaultPages = 50
+	// CrawlMaxPages 多页抓取允许的最大页数
+	CrawlMaxPages = 500
+	// CrawlDefaultConcurrency 多页抓取默认同时请求的页数 (上游请求还会经过限流)
+	CrawlDefaultConcurrency = 2
+	// CrawlMaxConcurrency 多页抓取允许同时请求的最大页数
+	CrawlMaxConcurrency = 8
+	// BatchMaxIDs 批量获取影片详情一次最多的 ID 数
+	BatchMaxIDs = 500
+	// BatchDefaultConcurrency 批量获取影片详情默认的并发数 (上游请求还会经过限流)
+	BatchDefaultConcurrency = 4
+	// BatchMaxConcurrency 批量获取影片详情允许的最大并发数
+	BatchMaxConcurrency = 8
+	// CatalogCacheExpire 类别目录很少变化，软过期时间比详情更长
+	CatalogCacheExpire = 30 * 24 * time.Hour
+	// CatalogStaleExpire 类别目录作为过期数据返回的最长时间
+	CatalogStaleExpire = 180 * 24 * time.Hour
+	// CacheRefreshBackoff 后台刷新失败后，同一个 key 在这段时间内不再重试
+	CacheRefreshBackoff = time.Minute
 )
 
 // PageReg 用于校验页码: 必须以 1-9 开头，后面跟任意数字
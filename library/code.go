@@ -0,0 +1,100 @@
+package library
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Match 从文件名中解析出的番号信息
+type Match struct {
+	// Code 规范化后的番号，如 ABP-123、FC2-PPV-1234567、010120-001
+	Code string `json:"code"`
+	// Part 分段序号 (CD1/CD2/part2)，未分段时为 0
+	Part int `json:"part"`
+	// Subtitle 文件名带 -C / -UC 等中文字幕标记
+	Subtitle bool `json:"subtitle"`
+	// Uncensored 文件名带 -U / -UC 等无码破解标记
+	Uncensored bool `json:"uncensored"`
+}
+
+// -------------------------------------------------------------
+// 正则表达式预编译
+// -------------------------------------------------------------
+var (
+	// 方括号、圆括号、中文括号中的标签，如 [FHD]、【中文字幕】、(1080p)
+	bracketRegex = regexp.MustCompile(`[\[【(（][^\]】)）]*[\]】)）]`)
+	// 站点前缀，如 hhd800.com@ABP-123、www.xxx.com-ABP-123
+	sitePrefixRegex = regexp.MustCompile(`^(?:WWW\.)?[A-Z0-9-]+\.(?:COM|NET|ORG|CC|TV|ME|XYZ|LA)[@_\- ]+`)
+	// FC2: FC2-PPV-1234567 / FC2PPV_1234567 / FC2-1234567
+	fc2Regex = regexp.MustCompile(`FC2[-_ ]?(?:PPV)?[-_ ]?(\d{5,8})`)
+	// HEYZO-1234
+	heyzoRegex = regexp.MustCompile(`HEYZO[-_ ]?(\d{4})`)
+	// 日期型无码番号: 010120-001 (加勒比) / 010120_001 (一本道)
+	dateCodeRegex = regexp.MustCompile(`(?:^|[^0-9])(\d{6})([-_])(\d{2,3})(?:[^0-9]|$)`)
+	// 普通番号: ABP-123 / ABP123 / abp00123 (DMM content id)
+	normalCodeRegex = regexp.MustCompile(`(?:^|[^A-Z])([A-Z]{2,6})[-_ ]?(\d{2,5})(?:[^0-9]|$)`)
+	// 分段: CD1 / PART2 / PT2 / DISC1
+	partRegex = regexp.MustCompile(`(?:CD|PART|PT|DIS[CK])[-_ ]?(\d{1,2})`)
+	// 番号后的单个数字分段: ABP-123-2
+	trailingPartRegex = regexp.MustCompile(`^[-_ ]([1-9])$`)
+	// 番号后的字幕 / 无码标记: -C / -CH / -UC / -U
+	flagRegex = regexp.MustCompile(`^[-_ ]?(UC|U|CH|C)(?:[^A-Z]|$)`)
+)
+
+// ParseFileName 从视频文件名中提取并规范化番号
+// 例如 abp-123.mp4、ABP123-C.mkv、[FHD]ssis-001_part2.mp4、FC2-PPV-1234567-CD1.mp4
+func ParseFileName(name string) (Match, bool) {
+	base := strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	base = strings.ToUpper(strings.TrimSpace(base))
+	base = bracketRegex.ReplaceAllString(base, " ")
+	base = sitePrefixRegex.ReplaceAllString(strings.TrimSpace(base), "")
+
+	var m Match
+	var rest string
+
+	switch {
+	case fc2Regex.MatchString(base):
+		loc := fc2Regex.FindStringSubmatchIndex(base)
+		m.Code = "FC2-PPV-" + base[loc[2]:loc[3]]
+		rest = base[loc[1]:]
+	case heyzoRegex.MatchString(base):
+		loc := heyzoRegex.FindStringSubmatchIndex(base)
+		m.Code = "HEYZO-" + base[loc[2]:loc[3]]
+		rest = base[loc[1]:]
+	case dateCodeRegex.MatchString(base):
+		loc := dateCodeRegex.FindStringSubmatchIndex(base)
+		m.Code = base[loc[2]:loc[3]] + base[loc[4]:loc[5]] + base[loc[6]:loc[7]]
+		rest = base[loc[7]:]
+	case normalCodeRegex.MatchString(base):
+		loc := normalCodeRegex.FindStringSubmatchIndex(base)
+		prefix := base[loc[2]:loc[3]]
+		num, _ := strconv.Atoi(base[loc[4]:loc[5]])
+		m.Code = fmt.Sprintf("%s-%03d", prefix, num)
+		rest = base[loc[5]:]
+	default:
+		return Match{}, false
+	}
+
+	// 解析番号之后的标记
+	if fm := flagRegex.FindStringSubmatch(rest); len(fm) > 1 {
+		switch fm[1] {
+		case "C", "CH":
+			m.Subtitle = true
+		case "U":
+			m.Uncensored = true
+		case "UC":
+			m.Subtitle = true
+			m.Uncensored = true
+		}
+	}
+	if pm := partRegex.FindStringSubmatch(rest); len(pm) > 1 {
+		m.Part, _ = strconv.Atoi(pm[1])
+	} else if pm := trailingPartRegex.FindStringSubmatch(rest); len(pm) > 1 {
+		m.Part, _ = strconv.Atoi(pm[1])
+	}
+
+	return m, true
+}
@@ -0,0 +1,44 @@
+package library
+
+import "testing"
+
+func TestParseFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want Match
+	}{
+		{"abp-123.mp4", Match{Code: "ABP-123"}},
+		{"ABP123-C.mkv", Match{Code: "ABP-123", Subtitle: true}},
+		{"[FHD]ssis-001_part2.mp4", Match{Code: "SSIS-001", Part: 2}},
+		{"SSIS-001-UC.mp4", Match{Code: "SSIS-001", Subtitle: true, Uncensored: true}},
+		{"ssis-001-U.mp4", Match{Code: "SSIS-001", Uncensored: true}},
+		{"IPX-456-CD1.avi", Match{Code: "IPX-456", Part: 1}},
+		{"IPX-456 cd2.avi", Match{Code: "IPX-456", Part: 2}},
+		{"ipx-456-2.mp4", Match{Code: "IPX-456", Part: 2}},
+		{"abp00123.mp4", Match{Code: "ABP-123"}},
+		{"hhd800.com@MIDV-018-C.mp4", Match{Code: "MIDV-018", Subtitle: true}},
+		{"【中文字幕】STARS-804.mp4", Match{Code: "STARS-804"}},
+		{"FC2-PPV-1234567.mp4", Match{Code: "FC2-PPV-1234567"}},
+		{"fc2ppv_1234567-cd2.mp4", Match{Code: "FC2-PPV-1234567", Part: 2}},
+		{"heyzo_1234.mp4", Match{Code: "HEYZO-1234"}},
+		{"010120-001-carib-1080p.mp4", Match{Code: "010120-001"}},
+		{"010120_001.mp4", Match{Code: "010120_001"}},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseFileName(tt.name)
+		if !ok {
+			t.Errorf("ParseFileName(%q) not matched", tt.name)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseFileName(%q) = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"holiday.mp4", "1080p.mkv", "readme.txt"} {
+		if got, ok := ParseFileName(name); ok {
+			t.Errorf("ParseFileName(%q) = %+v, want no match", name, got)
+		}
+	}
+}
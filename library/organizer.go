@@ -0,0 +1,666 @@
+package library
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+)
+
+// 冲突处理策略
+const (
+	ConflictSkip      = "skip"      // 目标已存在时跳过 (多段文件整组跳过)
+	ConflictRename    = "rename"    // 目标已存在时追加 (1)、(2) ...
+	ConflictOverwrite = "overwrite" // 覆盖目标文件，原文件改名备份，撤销时恢复
+)
+
+// 单次移动的状态
+const (
+	MoveStatusPlanned   = "planned"
+	MoveStatusMoved     = "moved"
+	MoveStatusSkipped   = "skipped"
+	MoveStatusUnchanged = "unchanged"
+	MoveStatusFailed    = "failed"
+	MoveStatusReverted  = "reverted"
+)
+
+var (
+	// ErrOrganizeRunning 已有整理或撤销任务在执行
+	ErrOrganizeRunning = errors.New("organize task is already running")
+	// ErrBatchNotFound 整理批次不存在
+	ErrBatchNotFound = errors.New("organize batch not found")
+	// ErrInvalidTemplate 整理模板不合法
+	ErrInvalidTemplate = errors.New("invalid organize template")
+)
+
+// subtitleExts 随视频一起移动的字幕文件
+var subtitleExts = map[string]bool{
+	".srt": true, ".ass": true, ".ssa": true, ".sub": true, ".idx": true,
+	".vtt": true, ".sup": true, ".smi": true,
+}
+
+var (
+	// 模板占位符: {id} {title} ...
+	placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+	// 模板最后一段中写死的扩展名，如 {id}.mp4
+	templateExtRegex = regexp.MustCompile(`\.[A-Za-z0-9]{2,4}$`)
+	// 文件名中不允许出现的字符
+	unsafeNameRegex = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]`)
+	spacesRegex     = regexp.MustCompile(`\s+`)
+)
+
+// maxSegmentBytes 每一级目录/文件名的最大字节数 (大多数文件系统限制为 255)
+const maxSegmentBytes = 200
+
+// OrganizeOptions 整理参数
+type OrganizeOptions struct {
+	// ItemIDs 需要整理的视频库记录，为空时整理所有已匹配的文件
+	ItemIDs  []uint `json:"ids"`
+	Template string `json:"template"`
+	Conflict string `json:"conflict" binding:"omitempty,oneof=skip rename overwrite"`
+	DryRun   bool   `json:"dryRun"`
+}
+
+// Move 一次文件移动
+type Move struct {
+	ItemID  uint   `json:"itemId"`
+	MovieID string `json:"movieId"`
+	Kind    string `json:"kind"` // video / subtitle
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	Backup  string `json:"backup,omitempty"` // 被覆盖的原目标文件的备份位置
+	Status  string `json:"status"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// OrganizeResult 整理 (或撤销) 的结果
+type OrganizeResult struct {
+	BatchID string `json:"batchId,omitempty"`
+	DryRun  bool   `json:"dryRun"`
+	Moves   []Move `json:"moves"`
+}
+
+// Organizer 按模板重命名、移动已匹配的视频文件，并记录可撤销的整理日志
+type Organizer struct {
+	cfg      config.LibraryConfig
+	provider scraper.Provider
+	mu       sync.Mutex
+}
+
+// NewOrganizer 创建整理器
+func NewOrganizer(cfg config.LibraryConfig, provider scraper.Provider) *Organizer {
+	return &Organizer{cfg: cfg, provider: provider}
+}
+
+// Organize 整理视频文件，DryRun 时只返回计划而不移动
+func (o *Organizer) Organize(opts OrganizeOptions) (*OrganizeResult, error) {
+	if !o.mu.TryLock() {
+		return nil, ErrOrganizeRunning
+	}
+	defer o.mu.Unlock()
+
+	if opts.Template == "" {
+		opts.Template = o.cfg.OrganizeTemplate
+	}
+	if opts.Conflict == "" {
+		opts.Conflict = o.cfg.OrganizeConflict
+	}
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	if err := ValidateTemplate(opts.Template); err != nil {
+		return nil, err
+	}
+
+	items, err := cachedb.FindMatchedLibraryItems(opts.ItemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一部影片的所有文件 (多段) 作为一组处理
+	groups := make(map[string][]cachedb.LibraryItem)
+	var movieIDs []string
+	for _, item := range items {
+		if _, ok := groups[item.MovieID]; !ok {
+			movieIDs = append(movieIDs, item.MovieID)
+		}
+		groups[item.MovieID] = append(groups[item.MovieID], item)
+	}
+	sort.Strings(movieIDs)
+
+	result := &OrganizeResult{DryRun: opts.DryRun, Moves: []Move{}}
+	planned := make(map[string]bool)
+	for _, movieID := range movieIDs {
+		result.Moves = append(result.Moves, o.planGroup(movieID, groups[movieID], opts, planned)...)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+
+	result.BatchID = newBatchID()
+	for i := range result.Moves {
+		move := &result.Moves[i]
+		if move.Status != MoveStatusPlanned {
+			continue
+		}
+		o.execute(result.BatchID, move)
+	}
+	return result, nil
+}
+
+// planGroup 计算一组文件的目标路径
+func (o *Organizer) planGroup(movieID string, items []cachedb.LibraryItem, opts OrganizeOptions, planned map[string]bool) []Move {
+	var moves []Move
+
+	detail, err := o.provider.GetMovieDetail(movieID)
+	if err != nil {
+		for _, item := range items {
+			moves = append(moves, Move{
+				ItemID: item.ID, MovieID: movieID, Kind: "video",
+				Source: item.Path, Status: MoveStatusFailed, Reason: err.Error(),
+			})
+		}
+		return moves
+	}
+
+	groupConflict := ""
+	for _, item := range items {
+		match := Match{Code: item.Code, Part: item.Part, Subtitle: item.Subtitle, Uncensored: item.Uncensored}
+		ext := filepath.Ext(item.Path)
+		rel, err := RenderTemplate(opts.Template, detail, match, ext)
+		if err != nil {
+			moves = append(moves, Move{ItemID: item.ID, MovieID: movieID, Kind: "video", Source: item.Path, Status: MoveStatusFailed, Reason: err.Error()})
+			continue
+		}
+		target, err := resolveTarget(o.rootFor(item.Path), rel)
+		if err != nil {
+			moves = append(moves, Move{ItemID: item.ID, MovieID: movieID, Kind: "video", Source: item.Path, Status: MoveStatusFailed, Reason: err.Error()})
+			continue
+		}
+
+		video := Move{ItemID: item.ID, MovieID: movieID, Kind: "video", Source: item.Path, Target: target, Status: MoveStatusPlanned}
+		if conflict := o.resolveConflict(&video, opts.Conflict, planned); conflict != "" {
+			groupConflict = conflict
+		}
+		moves = append(moves, video)
+
+		// 字幕文件跟随视频的新文件名
+		srcStem := strings.TrimSuffix(item.Path, ext)
+		dstStem := strings.TrimSuffix(video.Target, ext)
+		for _, sub := range findSidecars(item.Path) {
+			subMove := Move{
+				ItemID: item.ID, MovieID: movieID, Kind: "subtitle",
+				Source: sub, Target: dstStem + sub[len(srcStem):], Status: MoveStatusPlanned,
+			}
+			if conflict := o.resolveConflict(&subMove, opts.Conflict, planned); conflict != "" {
+				groupConflict = conflict
+			}
+			moves = append(moves, subMove)
+		}
+	}
+
+	// 多段文件保持在一起: 任意一个文件冲突则整组跳过
+	if groupConflict != "" {
+		for i := range moves {
+			if moves[i].Status == MoveStatusPlanned {
+				moves[i].Status = MoveStatusSkipped
+				moves[i].Reason = "group skipped: " + groupConflict
+			}
+		}
+	}
+	return moves
+}
+
+// resolveConflict 按策略处理目标已存在的情况，skip 策略下返回冲突原因
+func (o *Organizer) resolveConflict(move *Move, policy string, planned map[string]bool) string {
+	if move.Source == move.Target {
+		move.Status = MoveStatusUnchanged
+		return ""
+	}
+
+	exists := func(path string) bool {
+		if planned[path] {
+			return true
+		}
+		_, err := os.Lstat(path)
+		return err == nil
+	}
+
+	if exists(move.Target) {
+		switch policy {
+		case ConflictOverwrite:
+			if planned[move.Target] {
+				// 本次整理中的两个文件不能互相覆盖
+				move.Status = MoveStatusSkipped
+				move.Reason = "target planned by another file"
+				return move.Reason
+			}
+			move.Reason = "overwrite existing target (backed up)"
+		case ConflictRename:
+			ext := filepath.Ext(move.Target)
+			base := strings.TrimSuffix(move.Target, ext)
+			for i := 1; ; i++ {
+				candidate := fmt.Sprintf("%s (%d)%s", base, i, ext)
+				if !exists(candidate) {
+					move.Target = candidate
+					break
+				}
+			}
+		default:
+			move.Status = MoveStatusSkipped
+			move.Reason = "target exists: " + move.Target
+			return move.Reason
+		}
+	}
+	planned[move.Target] = true
+	return ""
+}
+
+// execute 执行移动并写入日志
+func (o *Organizer) execute(batchID string, move *Move) {
+	entry := &cachedb.OrganizeJournal{
+		BatchID:       batchID,
+		LibraryItemID: move.ItemID,
+		Kind:          move.Kind,
+		Source:        move.Source,
+		Target:        move.Target,
+		Status:        cachedb.JournalStatusMoved,
+	}
+
+	err := os.MkdirAll(filepath.Dir(move.Target), 0755)
+	if err == nil && fileExists(move.Target) {
+		// overwrite 策略: 先把原目标文件改名备份，撤销时恢复
+		backup := backupPath(move.Target, batchID)
+		if err = os.Rename(move.Target, backup); err == nil {
+			move.Backup = backup
+			entry.Backup = backup
+		}
+	}
+	if err == nil {
+		err = moveFile(move.Source, move.Target)
+		if err != nil && entry.Backup != "" {
+			if restoreErr := os.Rename(entry.Backup, move.Target); restoreErr != nil {
+				log.Printf("恢复被覆盖的文件失败 %s: %v", entry.Backup, restoreErr)
+			} else {
+				move.Backup, entry.Backup = "", ""
+			}
+		}
+	}
+	if err != nil {
+		move.Status = MoveStatusFailed
+		move.Reason = err.Error()
+		entry.Status = cachedb.JournalStatusFailed
+		entry.Error = err.Error()
+	} else {
+		move.Status = MoveStatusMoved
+		if move.Kind == "video" {
+			o.updateItemPath(move.ItemID, move.Target)
+		}
+	}
+
+	if err := cachedb.SaveJournal(entry); err != nil {
+		log.Printf("写入整理日志失败 %s -> %s: %v", move.Source, move.Target, err)
+	}
+}
+
+// Undo 撤销一次整理，按相反顺序把文件移回原位置
+func (o *Organizer) Undo(batchID string) (*OrganizeResult, error) {
+	if !o.mu.TryLock() {
+		return nil, ErrOrganizeRunning
+	}
+	defer o.mu.Unlock()
+
+	entries, err := cachedb.ListJournal(batchID)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrBatchNotFound, batchID)
+	}
+
+	result := &OrganizeResult{BatchID: batchID, Moves: []Move{}}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Status != cachedb.JournalStatusMoved {
+			continue
+		}
+
+		move := Move{ItemID: entry.LibraryItemID, Kind: entry.Kind, Source: entry.Target, Target: entry.Source}
+		switch {
+		case !fileExists(entry.Target):
+			move.Status = MoveStatusFailed
+			move.Reason = "file no longer exists: " + entry.Target
+		case fileExists(entry.Source):
+			move.Status = MoveStatusFailed
+			move.Reason = "original path is occupied: " + entry.Source
+		default:
+			err := os.MkdirAll(filepath.Dir(entry.Source), 0755)
+			if err == nil {
+				err = moveFile(entry.Target, entry.Source)
+			}
+			if err != nil {
+				move.Status = MoveStatusFailed
+				move.Reason = err.Error()
+				break
+			}
+
+			move.Status = MoveStatusReverted
+			now := time.Now()
+			entry.Status = cachedb.JournalStatusReverted
+			entry.RevertedAt = &now
+			if err := cachedb.SaveJournal(&entry); err != nil {
+				log.Printf("更新整理日志失败 %d: %v", entry.ID, err)
+			}
+			if entry.Kind == "video" {
+				o.updateItemPath(entry.LibraryItemID, entry.Source)
+			}
+			// 恢复被覆盖的原文件 (需要重新扫描才会回到视频库)
+			if entry.Backup != "" {
+				if err := os.Rename(entry.Backup, entry.Target); err != nil {
+					move.Reason = "restore overwritten file failed: " + err.Error()
+					log.Printf("恢复被覆盖的文件失败 %s: %v", entry.Backup, err)
+				}
+			}
+			removeEmptyDirs(filepath.Dir(entry.Target), o.rootFor(entry.Target))
+		}
+		result.Moves = append(result.Moves, move)
+	}
+	return result, nil
+}
+
+// updateItemPath 文件移动后同步视频库记录
+func (o *Organizer) updateItemPath(itemID uint, path string) {
+	item, err := cachedb.GetLibraryItem(itemID)
+	if err != nil || item == nil {
+		return
+	}
+	// 覆盖了另一个已入库的文件时删除它的记录
+	if other, err := cachedb.GetLibraryItemByPath(path); err == nil && other != nil && other.ID != itemID {
+		if err := cachedb.DeleteLibraryItem(other.ID); err != nil {
+			log.Printf("删除被覆盖的视频库记录失败 %d: %v", other.ID, err)
+		}
+	}
+	item.Path = path
+	item.Dir = filepath.Dir(path)
+	item.FileName = filepath.Base(path)
+	if err := cachedb.SaveLibraryItem(item); err != nil {
+		log.Printf("更新视频库记录失败 %d: %v", itemID, err)
+	}
+}
+
+// resolveTarget 把模板渲染出的相对路径放到整理根目录下，结果必须仍在根目录内
+func resolveTarget(root, rel string) (string, error) {
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return "", fmt.Errorf("%w: absolute path is not allowed: %s", ErrInvalidTemplate, rel)
+	}
+	root = filepath.Clean(root)
+	target := filepath.Join(root, rel)
+	if r, err := filepath.Rel(root, target); err != nil || r == "." || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: path escapes organize directory: %s", ErrInvalidTemplate, rel)
+	}
+	return target, nil
+}
+
+// backupPath 被覆盖文件的备份位置: 同目录下追加 .overwritten-批次号
+func backupPath(target, batchID string) string {
+	return target + ".overwritten-" + batchID
+}
+
+// rootFor 整理的根目录: 配置了 ORGANIZE_DIR 时使用它，否则使用文件所在的扫描目录
+func (o *Organizer) rootFor(path string) string {
+	if o.cfg.OrganizeDir != "" {
+		return o.cfg.OrganizeDir
+	}
+	root := ""
+	for _, dir := range o.cfg.Dirs {
+		dir = filepath.Clean(dir)
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) && len(dir) > len(root) {
+			root = dir
+		}
+	}
+	if root == "" {
+		root = filepath.Dir(path)
+	}
+	return root
+}
+
+// ==========================================
+// 模板
+// ==========================================
+
+// ValidateTemplate 检查模板中的占位符是否都受支持，模板必须是相对于整理根目录的路径
+func ValidateTemplate(tmpl string) error {
+	if strings.TrimSpace(tmpl) == "" {
+		return fmt.Errorf("%w: template is empty", ErrInvalidTemplate)
+	}
+	if slashed := filepath.ToSlash(tmpl); strings.HasPrefix(slashed, "/") || filepath.IsAbs(tmpl) || filepath.VolumeName(tmpl) != "" {
+		return fmt.Errorf("%w: template must be a relative path", ErrInvalidTemplate)
+	}
+	for _, seg := range strings.Split(filepath.ToSlash(tmpl), "/") {
+		if seg == ".." {
+			return fmt.Errorf("%w: template must not contain ..", ErrInvalidTemplate)
+		}
+	}
+	for _, m := range placeholderRegex.FindAllStringSubmatch(tmpl, -1) {
+		if _, ok := templateFields(&model.MovieDetail{})[m[1]]; !ok {
+			return fmt.Errorf("%w: unknown field {%s}", ErrInvalidTemplate, m[1])
+		}
+	}
+	return nil
+}
+
+// RenderTemplate 根据影片信息渲染目标相对路径 (包含扩展名)
+// 支持 {id} {title} {studio} {label} {director} {series} {star} {stars} {date} {year}
+// 文件名会自动追加 -C / -U / -UC 标记和 -cd1 分段后缀，模板最后写死的扩展名会被替换为原文件扩展名
+func RenderTemplate(tmpl string, detail *model.MovieDetail, match Match, ext string) (string, error) {
+	if err := ValidateTemplate(tmpl); err != nil {
+		return "", err
+	}
+	fields := templateFields(detail)
+
+	segments := strings.Split(filepath.ToSlash(tmpl), "/")
+	last := len(segments) - 1
+	segments[last] = templateExtRegex.ReplaceAllString(segments[last], "")
+
+	var rendered []string
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		value := placeholderRegex.ReplaceAllStringFunc(seg, func(ph string) string {
+			return fields[ph[1:len(ph)-1]]
+		})
+		value = sanitizeSegment(value)
+		if value == "" {
+			value = "Unknown"
+		}
+		if i == last {
+			value += fileSuffix(match) + ext
+		}
+		rendered = append(rendered, value)
+	}
+
+	path := strings.Join(rendered, "/")
+	if path == "" || strings.HasSuffix(path, "/") {
+		return "", fmt.Errorf("%w: empty file name", ErrInvalidTemplate)
+	}
+	return filepath.FromSlash(path), nil
+}
+
+func templateFields(detail *model.MovieDetail) map[string]string {
+	name := func(p *model.Property) string {
+		if p == nil {
+			return ""
+		}
+		return p.Name
+	}
+
+	// JavBus 的标题以番号开头，{title} 中去掉番号避免重复
+	title := strings.TrimSpace(strings.TrimPrefix(detail.Title, detail.ID))
+
+	var stars []string
+	for _, s := range detail.Stars {
+		stars = append(stars, s.Name)
+	}
+	star := ""
+	if len(stars) > 0 {
+		star = stars[0]
+	}
+	if len(stars) > 3 {
+		stars = append(stars[:3], "等")
+	}
+
+	year := ""
+	if len(detail.Date) >= 4 {
+		year = detail.Date[:4]
+	}
+
+	return map[string]string{
+		"id":       detail.ID,
+		"title":    title,
+		"studio":   name(detail.Producer),
+		"label":    name(detail.Publisher),
+		"director": name(detail.Director),
+		"series":   name(detail.Series),
+		"star":     star,
+		"stars":    strings.Join(stars, ","),
+		"date":     detail.Date,
+		"year":     year,
+	}
+}
+
+// fileSuffix 字幕 / 无码标记与分段后缀
+func fileSuffix(match Match) string {
+	suffix := ""
+	switch {
+	case match.Subtitle && match.Uncensored:
+		suffix = "-UC"
+	case match.Subtitle:
+		suffix = "-C"
+	case match.Uncensored:
+		suffix = "-U"
+	}
+	if match.Part > 0 {
+		suffix += fmt.Sprintf("-cd%d", match.Part)
+	}
+	return suffix
+}
+
+// sanitizeSegment 去掉文件名中的非法字符并限制长度
+func sanitizeSegment(s string) string {
+	s = unsafeNameRegex.ReplaceAllString(s, " ")
+	s = spacesRegex.ReplaceAllString(s, " ")
+	s = strings.Trim(s, " .")
+	if len(s) > maxSegmentBytes {
+		cut := maxSegmentBytes
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = strings.TrimRight(s[:cut], " .")
+	}
+	return s
+}
+
+// ==========================================
+// 文件操作
+// ==========================================
+
+// findSidecars 查找与视频同名的字幕文件 (包括 xxx.zh.srt 这种带语言后缀的)
+func findSidecars(videoPath string) []string {
+	dir := filepath.Dir(videoPath)
+	stem := strings.ToLower(strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath)))
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var sidecars []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		lower := strings.ToLower(name)
+		if !subtitleExts[filepath.Ext(lower)] {
+			continue
+		}
+		if strings.TrimSuffix(lower, filepath.Ext(lower)) == stem || strings.HasPrefix(lower, stem+".") {
+			sidecars = append(sidecars, filepath.Join(dir, name))
+		}
+	}
+	return sidecars
+}
+
+// moveFile 移动文件，跨文件系统时退化为复制后删除
+func moveFile(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil || !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	_ = os.Chtimes(dst, info.ModTime(), info.ModTime())
+	in.Close()
+	return os.Remove(src)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+// removeEmptyDirs 撤销后清理整理时创建的空目录，不会越过 root
+func removeEmptyDirs(dir, root string) {
+	root = filepath.Clean(root)
+	for dir = filepath.Clean(dir); dir != root && strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+	}
+}
+
+// newBatchID 生成整理批次号: 时间 + 随机串
+func newBatchID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b)
+}
@@ -0,0 +1,203 @@
+package library
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	detail := &model.MovieDetail{
+		ID:       "ABP-123",
+		Title:    "ABP-123 タイトル: 前編/後編",
+		Date:     "2014-04-01",
+		Producer: &model.Property{Name: "プレステージ"},
+		Stars:    []model.Property{{Name: "A"}, {Name: "B"}},
+	}
+
+	tests := []struct {
+		tmpl  string
+		match Match
+		want  string
+	}{
+		{"{studio}/{id} {title}/{id}", Match{Code: "ABP-123"}, "プレステージ/ABP-123 タイトル 前編 後編/ABP-123.mp4"},
+		{"{year}/{id}.mkv", Match{Code: "ABP-123", Part: 2, Subtitle: true}, "2014/ABP-123-C-cd2.mp4"},
+		{"{series}/{stars}/{id}", Match{Code: "ABP-123"}, "Unknown/A,B/ABP-123.mp4"},
+	}
+	for _, tt := range tests {
+		got, err := RenderTemplate(tt.tmpl, detail, tt.match, ".mp4")
+		if err != nil {
+			t.Errorf("RenderTemplate(%q) error = %v", tt.tmpl, err)
+			continue
+		}
+		if got != filepath.FromSlash(tt.want) {
+			t.Errorf("RenderTemplate(%q) = %q, want %q", tt.tmpl, got, tt.want)
+		}
+	}
+
+	if _, err := RenderTemplate("{id}/{unknown}", detail, Match{}, ".mp4"); err == nil {
+		t.Error("unknown placeholder should fail")
+	}
+
+	// 模板只能是整理根目录下的相对路径
+	for _, tmpl := range []string{"/tmp/{id}", "../{id}", "{studio}/../../{id}"} {
+		if _, err := RenderTemplate(tmpl, detail, Match{}, ".mp4"); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("RenderTemplate(%q) error = %v, want ErrInvalidTemplate", tmpl, err)
+		}
+	}
+	root := filepath.Join(t.TempDir(), "lib")
+	for _, rel := range []string{"..", filepath.Join("a", "..", "..", "b"), filepath.Join(root, "abs.mp4")} {
+		if _, err := resolveTarget(root, rel); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("resolveTarget(%q) error = %v, want ErrInvalidTemplate", rel, err)
+		}
+	}
+	if got, err := resolveTarget(root, filepath.Join("a", "b.mp4")); err != nil || got != filepath.Join(root, "a", "b.mp4") {
+		t.Errorf("resolveTarget() = %q, %v", got, err)
+	}
+}
+
+func TestOrganizeAndUndo(t *testing.T) {
+	setupTestDB(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, "abp123-cd1.mp4", "abp123-cd1.zh.srt", "ABP-123-cd2.mp4")
+
+	cfg := config.LibraryConfig{Dirs: []string{dir}, Extensions: []string{".mp4"}, OrganizeTemplate: "{id} {title}/{id}"}
+	provider := &fakeProvider{}
+	if _, err := NewScanner(cfg, provider).Scan(false); err != nil {
+		t.Fatal(err)
+	}
+	organizer := NewOrganizer(cfg, provider)
+
+	// dry-run 不移动文件
+	plan, err := organizer.Organize(OrganizeOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Moves) != 3 || plan.BatchID != "" {
+		t.Fatalf("dry-run moves = %+v", plan.Moves)
+	}
+	for _, m := range plan.Moves {
+		if m.Status != MoveStatusPlanned {
+			t.Errorf("dry-run move %s status = %s", m.Source, m.Status)
+		}
+	}
+	if !fileExists(filepath.Join(dir, "abp123-cd1.mp4")) {
+		t.Fatal("dry-run moved files")
+	}
+
+	result, err := organizer.Organize(OrganizeOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ABP-123 title/ABP-123-cd1.mp4", "ABP-123 title/ABP-123-cd1.zh.srt", "ABP-123 title/ABP-123-cd2.mp4"}
+	for _, name := range want {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s not found after organize", name)
+		}
+	}
+	matched := true
+	items, _, _ := cachedb.ListLibraryItems(cachedb.LibraryQuery{Matched: &matched})
+	for _, item := range items {
+		if !strings.HasPrefix(item.Path, filepath.Join(dir, "ABP-123 title")) {
+			t.Errorf("library item path not updated: %s", item.Path)
+		}
+	}
+
+	// 目标已存在时 skip 策略整组跳过
+	writeFiles(t, dir, "again/abp-123-cd1.mp4")
+	if _, err := NewScanner(cfg, provider).Scan(false); err != nil {
+		t.Fatal(err)
+	}
+	skipped, err := organizer.Organize(OrganizeOptions{Conflict: ConflictSkip})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range skipped.Moves {
+		if m.Status == MoveStatusMoved {
+			t.Errorf("move %s should not happen under skip policy", m.Source)
+		}
+	}
+
+	// 撤销后文件回到原位置，新建的空目录被删除
+	undo, err := organizer.Undo(result.BatchID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undo.Moves) != 3 {
+		t.Errorf("undo moves = %+v", undo.Moves)
+	}
+	for _, name := range []string{"abp123-cd1.mp4", "abp123-cd1.zh.srt", "ABP-123-cd2.mp4"} {
+		if !fileExists(filepath.Join(dir, name)) {
+			t.Errorf("%s not restored", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "ABP-123 title")); !os.IsNotExist(err) {
+		t.Errorf("empty target dir should be removed, err = %v", err)
+	}
+
+	batches, err := cachedb.ListOrganizeBatches(10)
+	if err != nil || len(batches) == 0 || batches[0].Reverted != 3 {
+		t.Errorf("ListOrganizeBatches() = %+v, err = %v", batches, err)
+	}
+}
+
+func TestOrganizeOverwrite(t *testing.T) {
+	setupTestDB(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, "abp-123.mp4")
+
+	cfg := config.LibraryConfig{Dirs: []string{dir}, Extensions: []string{".mp4"}, OrganizeTemplate: "{id}/{id}"}
+	provider := &fakeProvider{}
+	if _, err := NewScanner(cfg, provider).Scan(false); err != nil {
+		t.Fatal(err)
+	}
+	// 扫描之后目标位置才出现的文件
+	existing := filepath.Join(dir, "ABP-123", "ABP-123.mp4")
+	writeFiles(t, dir, "ABP-123/ABP-123.mp4")
+	if err := os.WriteFile(existing, []byte("existing"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	organizer := NewOrganizer(cfg, provider)
+
+	// 被覆盖的文件改名备份，记录在日志中
+	result, err := organizer.Organize(OrganizeOptions{Conflict: ConflictOverwrite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backup string
+	for _, m := range result.Moves {
+		if m.Status == MoveStatusMoved && m.Target == existing {
+			backup = m.Backup
+		}
+	}
+	if backup == "" {
+		t.Fatalf("overwrite moves = %+v", result.Moves)
+	}
+	if data, err := os.ReadFile(backup); err != nil || string(data) != "existing" {
+		t.Errorf("backup content = %q, err = %v", data, err)
+	}
+	entries, err := cachedb.ListJournal(result.BatchID)
+	if err != nil || len(entries) != 1 || entries[0].Backup != backup {
+		t.Errorf("journal = %+v, err = %v", entries, err)
+	}
+
+	// 撤销后原文件恢复
+	if _, err := organizer.Undo(result.BatchID); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(existing); err != nil || string(data) != "existing" {
+		t.Errorf("overwritten file not restored: %q, err = %v", data, err)
+	}
+	if fileExists(backup) {
+		t.Error("backup should be moved back")
+	}
+	if !fileExists(filepath.Join(dir, "abp-123.mp4")) {
+		t.Error("source not restored")
+	}
+}
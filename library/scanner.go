@@ -0,0 +1,255 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+)
+
+// ErrScanRunning 已有扫描任务在执行
+var ErrScanRunning = errors.New("library scan is already running")
+
+// ScanStatus 扫描进度与结果统计
+type ScanStatus struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt"`
+	Files      int        `json:"files"`     // 扫描到的视频文件数
+	Matched    int        `json:"matched"`   // 本次新匹配成功
+	Unmatched  int        `json:"unmatched"` // 文件名中没有番号或查询不到
+	Failed     int        `json:"failed"`    // 查询出错
+	Skipped    int        `json:"skipped"`   // 已匹配且未变化，跳过查询
+	Removed    int64      `json:"removed"`   // 文件已不存在而被删除的记录
+	Errors     []string   `json:"errors,omitempty"`
+}
+
+// Scanner 本地视频库扫描器
+// 遍历配置的目录，从文件名中解析番号，通过数据源获取影片详情并把匹配结果写入数据库
+type Scanner struct {
+	cfg      config.LibraryConfig
+	provider scraper.Provider
+
+	mu     sync.RWMutex
+	status ScanStatus
+}
+
+// NewScanner 创建扫描器
+func NewScanner(cfg config.LibraryConfig, provider scraper.Provider) *Scanner {
+	return &Scanner{cfg: cfg, provider: provider}
+}
+
+// Dirs 返回配置的扫描目录
+func (s *Scanner) Dirs() []string {
+	return s.cfg.Dirs
+}
+
+// Status 返回当前 (或最近一次) 扫描的状态
+func (s *Scanner) Status() ScanStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+// Start 在后台开始扫描，已有扫描在执行时返回 ErrScanRunning
+// force 为 true 时已匹配的文件也会重新查询
+func (s *Scanner) Start(force bool) error {
+	if err := s.begin(); err != nil {
+		return err
+	}
+	go s.run(force)
+	return nil
+}
+
+// Scan 同步执行一次扫描
+func (s *Scanner) Scan(force bool) (ScanStatus, error) {
+	if err := s.begin(); err != nil {
+		return ScanStatus{}, err
+	}
+	s.run(force)
+	return s.Status(), nil
+}
+
+func (s *Scanner) begin() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.Running {
+		return ErrScanRunning
+	}
+	now := time.Now()
+	s.status = ScanStatus{Running: true, StartedAt: &now}
+	return nil
+}
+
+func (s *Scanner) run(force bool) {
+	startedAt := *s.Status().StartedAt
+	// 同一次扫描中相同番号只查询一次 (多段文件)
+	lookups := make(map[string]lookupResult)
+	allDirsOK := len(s.cfg.Dirs) > 0
+
+	for _, dir := range s.cfg.Dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				s.addError(fmt.Sprintf("%s: %v", path, err))
+				if path == dir {
+					return err
+				}
+				return nil
+			}
+			if d.IsDir() {
+				// 跳过隐藏目录 (.git / .@__thumb 等)
+				if path != dir && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !s.isVideo(d.Name()) {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				s.addError(fmt.Sprintf("%s: %v", path, err))
+				return nil
+			}
+			s.scanFile(path, info, force, lookups)
+			return nil
+		})
+		if err != nil {
+			allDirsOK = false
+		}
+	}
+
+	// 只有所有目录都能正常访问时才清理已消失的文件，避免挂载盘离线时把记录全部删掉
+	var removed int64
+	if allDirsOK {
+		var err error
+		if removed, err = cachedb.DeleteMissingLibraryItems(startedAt); err != nil {
+			s.addError(fmt.Sprintf("remove missing items: %v", err))
+		}
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	s.status.Running = false
+	s.status.FinishedAt = &now
+	s.status.Removed = removed
+	status := s.status
+	s.mu.Unlock()
+
+	log.Printf("视频库扫描完成: 文件 %d, 新匹配 %d, 未匹配 %d, 失败 %d, 跳过 %d, 删除 %d",
+		status.Files, status.Matched, status.Unmatched, status.Failed, status.Skipped, status.Removed)
+}
+
+type lookupResult struct {
+	detail *model.MovieDetail
+	err    error
+}
+
+// scanFile 处理单个视频文件
+func (s *Scanner) scanFile(path string, info fs.FileInfo, force bool, lookups map[string]lookupResult) {
+	now := time.Now()
+	modTime := info.ModTime().UTC().Truncate(time.Second)
+
+	item, err := cachedb.GetLibraryItemByPath(path)
+	if err != nil {
+		s.addError(fmt.Sprintf("%s: %v", path, err))
+		return
+	}
+
+	// 已匹配且文件未变化，只更新扫描时间
+	if item != nil && item.Matched && !force && item.Size == info.Size() && item.ModTime.Equal(modTime) {
+		item.ScannedAt = now
+		if err := cachedb.SaveLibraryItem(item); err != nil {
+			s.addError(fmt.Sprintf("%s: %v", path, err))
+		}
+		s.count(func(st *ScanStatus) { st.Files++; st.Skipped++ })
+		return
+	}
+
+	if item == nil {
+		item = &cachedb.LibraryItem{}
+	}
+	item.Path = path
+	item.Dir = filepath.Dir(path)
+	item.FileName = filepath.Base(path)
+	item.Size = info.Size()
+	item.ModTime = modTime
+	item.ScannedAt = now
+	item.Matched = false
+	item.MovieID = ""
+	item.Title = ""
+	item.Error = ""
+	item.MatchedAt = nil
+
+	match, ok := ParseFileName(item.FileName)
+	item.Code = match.Code
+	item.Part = match.Part
+	item.Subtitle = match.Subtitle
+	item.Uncensored = match.Uncensored
+
+	var counter func(st *ScanStatus)
+	if !ok {
+		item.Error = "no movie code found in file name"
+		counter = func(st *ScanStatus) { st.Files++; st.Unmatched++ }
+	} else {
+		result, cached := lookups[match.Code]
+		if !cached {
+			result.detail, result.err = s.provider.GetMovieDetail(match.Code)
+			lookups[match.Code] = result
+		}
+
+		switch {
+		case errors.Is(result.err, scraper.ErrNotFound):
+			item.Error = "movie not found"
+			counter = func(st *ScanStatus) { st.Files++; st.Unmatched++ }
+		case result.err != nil:
+			item.Error = result.err.Error()
+			counter = func(st *ScanStatus) { st.Files++; st.Failed++ }
+		default:
+			item.Matched = true
+			item.MovieID = result.detail.ID
+			item.Title = result.detail.Title
+			item.MatchedAt = &now
+			counter = func(st *ScanStatus) { st.Files++; st.Matched++ }
+		}
+	}
+
+	if err := cachedb.SaveLibraryItem(item); err != nil {
+		s.addError(fmt.Sprintf("%s: %v", path, err))
+	}
+	s.count(counter)
+}
+
+func (s *Scanner) isVideo(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range s.cfg.Extensions {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) count(fn func(st *ScanStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.status)
+}
+
+// addError 记录错误，最多保留 50 条
+func (s *Scanner) addError(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.status.Errors) < 50 {
+		s.status.Errors = append(s.status.Errors, msg)
+	}
+}
@@ -0,0 +1,112 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+)
+
+// fakeProvider 只认识 ABP-123，其余番号返回 404
+type fakeProvider struct {
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	return &model.MoviesPage{}, nil
+}
+
+func (p *fakeProvider) GetMoviesByKeywordAndPage(keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
+	return &model.SearchMoviesPage{}, nil
+}
+
+func (p *fakeProvider) GetMovieDetail(id string) (*model.MovieDetail, error) {
+	p.calls++
+	if id == "ABP-123" {
+		return &model.MovieDetail{ID: id, Title: "ABP-123 title"}, nil
+	}
+	return nil, &scraper.UpstreamError{Kind: scraper.ErrNotFound, StatusCode: 404}
+}
+
+func (p *fakeProvider) GetStarInfo(starId string, movieType string) (*model.StarInfo, error) {
+	return &model.StarInfo{ID: starId}, nil
+}
+
+func (p *fakeProvider) GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error) {
+	return nil, nil
+}
+
+// setupTestDB 使用临时 sqlite 数据库
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	db, err := cachedb.InitDataBase(config.DatabaseConfig{
+		DBType:       "sqlite",
+		DBServerPath: filepath.Join(t.TempDir(), "library.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachedb.CacheDb = db
+	t.Cleanup(func() { cachedb.CacheDb = nil })
+}
+
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanner(t *testing.T) {
+	setupTestDB(t)
+	dir := t.TempDir()
+	writeFiles(t, dir, "abp123-cd1.mp4", "sub/ABP-123-cd2.mkv", "ipx-999.mp4", "holiday.mp4", "notes.txt", ".hidden/abp-123.mp4")
+
+	provider := &fakeProvider{}
+	scanner := NewScanner(config.LibraryConfig{Dirs: []string{dir}, Extensions: []string{".mp4", ".mkv"}}, provider)
+
+	status, err := scanner.Scan(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Files != 4 || status.Matched != 2 || status.Unmatched != 2 {
+		t.Errorf("first scan status = %+v", status)
+	}
+	// 两个分段共用一次查询
+	if provider.calls != 2 {
+		t.Errorf("provider calls = %d, want 2", provider.calls)
+	}
+
+	matched := true
+	items, total, err := cachedb.ListLibraryItems(cachedb.LibraryQuery{Matched: &matched})
+	if err != nil || total != 2 {
+		t.Fatalf("ListLibraryItems() total = %d, err = %v", total, err)
+	}
+	if items[0].MovieID != "ABP-123" || items[0].Part != 1 || items[1].Part != 2 {
+		t.Errorf("unexpected items: %+v", items)
+	}
+
+	// 再次扫描: 已匹配的文件跳过查询，删除的文件被清理
+	if err := os.Remove(filepath.Join(dir, "ipx-999.mp4")); err != nil {
+		t.Fatal(err)
+	}
+	status, err = scanner.Scan(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Skipped != 2 || status.Removed != 1 {
+		t.Errorf("second scan status = %+v", status)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/fireinrain/javbus-api/api"
 	"github.com/fireinrain/javbus-api/cachedb"
 	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/scheduler"
 )
 
 func main() {
@@ -27,10 +28,12 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("初始化数据库失败: %v", err))
 	}
-	_ = db
 	//初始化dao层
-
-	//启动定时任务
+	if purged, err := cachedb.PurgeExpiredCache(); err != nil {
+		log.Printf("清理过期缓存失败: %v", err)
+	} else if purged > 0 {
+		log.Printf("已清理 %d 条过期缓存", purged)
+	}
 
 	// 创建Gin服务器
 	addr := fmt.Sprintf(":%d", conf.Server.ServerPort)
@@ -38,11 +41,20 @@ func main() {
 		Addr:    addr,
 		Handler: api.SetupRouter(conf), // 修改RunApiServer为SetupRouter返回路由器
 	}
+
+	//启动定时任务 (依赖 SetupRouter 中创建的服务)
+	sched := newScheduler(conf)
+	sched.Start()
+	// 继续投递上次未完成的 webhook
+	api.WebhookDispatcher.Resume()
+
 	// 启动服务
-	log.Printf("Server starting on %s", addr)
-	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		panic(fmt.Sprintf("Server failed: %v", err))
-	}
+	go func() {
+		log.Printf("Server starting on %s", addr)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(fmt.Sprintf("Server failed: %v", err))
+		}
+	}()
 
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
@@ -55,8 +67,54 @@ func main() {
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("服务器强制关闭: %v", err)
+		log.Printf("服务器强制关闭: %v", err)
+	}
+	//关闭定时任务 关闭db
+	sched.Stop()
+	api.WebhookDispatcher.Stop()
+	if api.JavbusScraper.Proxies != nil {
+		api.JavbusScraper.Proxies.Stop()
 	}
-	//关闭db 关闭cache 关闭定时任务
+	if sqlDB, err := db.DB(); err == nil {
+		_ = sqlDB.Close()
+	}
+}
+
+// newScheduler 注册定时任务
+func newScheduler(conf *config.Config) *scheduler.Scheduler {
+	sched := scheduler.New()
+	if !conf.Scheduler.Enabled {
+		return sched
+	}
+
+	// 新片订阅检查
+	sched.Add(scheduler.Job{
+		Name:     "subscriptions",
+		Interval: time.Duration(conf.Scheduler.SubscriptionInterval) * time.Minute,
+		Delay:    30 * time.Second,
+		Run:      api.SubscriptionService.CheckAll,
+	})
+
+	// 磁力监控
+	sched.Add(scheduler.Job{
+		Name:     "magnet-watch",
+		Interval: time.Duration(conf.Scheduler.MagnetWatchInterval) * time.Minute,
+		Delay:    time.Minute,
+		Run:      api.MagnetWatcher.CheckAll,
+	})
 
+	// 清理数据库中的过期缓存
+	sched.Add(scheduler.Job{
+		Name:     "purge-cache",
+		Interval: time.Duration(conf.Scheduler.CachePurgeInterval) * time.Minute,
+		Delay:    time.Duration(conf.Scheduler.CachePurgeInterval) * time.Minute,
+		Run: func(ctx context.Context) {
+			if purged, err := cachedb.PurgeExpiredCache(); err != nil {
+				log.Printf("清理过期缓存失败: %v", err)
+			} else if purged > 0 {
+				log.Printf("已清理 %d 条过期缓存", purged)
+			}
+		},
+	})
+	return sched
 }
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace 所有指标的统一前缀
+const namespace = "javbus_api"
+
+var (
+	// HTTPRequestsTotal API 请求数 (按 Gin 路由模板统计，避免 ID 造成标签爆炸)
+	HTTPRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Total number of HTTP requests handled, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration API 请求耗时
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency in seconds, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// UpstreamRequestsTotal 发往上游 (JavBus) 的每一次请求，包括重试，status 为 error 表示网络错误
+	UpstreamRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_requests_total",
+		Help:      "Total number of upstream requests sent by the resty client, by host and status code.",
+	}, []string{"host", "status"})
+
+	// UpstreamRequestDuration 上游请求耗时
+	UpstreamRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Upstream request latency in seconds, by host.",
+		Buckets:   []float64{.1, .25, .5, 1, 2, 3, 5, 10},
+	}, []string{"host"})
+
+	// UpstreamRetriesTotal resty 重试次数
+	UpstreamRetriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_retries_total",
+		Help:      "Total number of upstream request retries, by host.",
+	}, []string{"host"})
+
+	// DocumentRequestsTotal requestDocument 拉取页面的最终结果 (重试之后)
+	DocumentRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "document_requests_total",
+		Help:      "Total number of page fetches made by requestDocument, by final status code.",
+	}, []string{"status"})
+
+	// CacheRequestsTotal 缓存查询结果，layer 为 memory 或 database
+	CacheRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_requests_total",
+		Help:      "Total number of cache lookups, by layer, key namespace and result (hit/miss/stale).",
+	}, []string{"layer", "namespace", "result"})
+
+	// CoalescedRequestsTotal 合并到其他进行中请求的次数 (没有单独请求上游)
+	CoalescedRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "coalesced_requests_total",
+		Help:      "Total number of scrapes that shared an in-flight upstream request instead of issuing their own, by key namespace.",
+	}, []string{"namespace"})
+
+	// CacheEvictionsTotal 内存缓存因超过上限被淘汰的条目数
+	CacheEvictionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_evictions_total",
+		Help:      "Total number of in-memory cache entries evicted by the LRU policy, by key namespace.",
+	}, []string{"namespace"})
+
+	// CacheRefreshTotal 过期缓存后台刷新结果 (ok / error)
+	CacheRefreshTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_refresh_total",
+		Help:      "Total number of background refreshes of stale cache entries, by key namespace and result (ok/error).",
+	}, []string{"namespace", "result"})
+
+	// ProxyRequestsTotal 代理池中每个代理的请求结果 (ok / failover / error)
+	ProxyRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "proxy_requests_total",
+		Help:      "Total number of requests sent through each pooled proxy, by result (ok/failover/error).",
+	}, []string{"proxy", "result"})
+
+	// ProxyUp 代理是否可用 (1 可用 / 0 不可用)
+	ProxyUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "proxy_up",
+		Help:      "Whether a pooled proxy is currently considered healthy (1) or not (0).",
+	}, []string{"proxy"})
+
+	// RateLimitWaitSeconds 请求在限流 (并发、令牌桶、随机等待) 上等待的时间
+	RateLimitWaitSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ratelimit_wait_seconds",
+		Help:      "Time upstream requests spent waiting on the client-side rate limiter, by host.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2, 5, 10},
+	}, []string{"host"})
+
+	// UpstreamBackoffsTotal 上游返回 403/429/503 后暂停请求的次数
+	UpstreamBackoffsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_backoffs_total",
+		Help:      "Total number of times requests to a host were paused after a 403/429/503 response, by host and status.",
+	}, []string{"host", "status"})
+
+	// ImageProbeTotal 封面尺寸探测结果 (ok / error / timeout)
+	ImageProbeTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "image_probe_total",
+		Help:      "Total number of cover image dimension probes, by result (ok/error/timeout).",
+	}, []string{"result"})
+)
+
+// StatusLabel 状态码转标签，err 不为空时返回 error
+func StatusLabel(code int, err error) string {
+	if err != nil {
+		return "error"
+	}
+	return strconv.Itoa(code)
+}
+
+// ObserveCache 记录一次缓存查询
+func ObserveCache(layer, namespace string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	ObserveCacheResult(layer, namespace, result)
+}
+
+// ObserveCacheResult 记录一次缓存查询，result 为 hit / miss / stale
+func ObserveCacheResult(layer, namespace, result string) {
+	CacheRequestsTotal.WithLabelValues(layer, namespace, result).Inc()
+}
+
+// RegisterGaugeFunc 注册一个按需计算的 Gauge，供其他包暴露内部状态 (比如缓存条目数)
+func RegisterGaugeFunc(name, help string, fn func() float64) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+}
+
+// GinMiddleware 统计每个路由的请求数和耗时
+func GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "NoRoute"
+		}
+		method := c.Request.Method
+		HTTPRequestsTotal.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		HTTPRequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler 返回 Prometheus 抓取接口
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -141,6 +141,29 @@ type StarInfo struct {
 	Hobby      string `json:"hobby"`      // nullable
 }
 
+// Star 演员列表、演员搜索中的演员，详细信息通过 StarInfo 获取
+type Star struct {
+	Avatar string `json:"avatar"` // nullable
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+}
+
+// ==========================================
+// 类别目录结构 (Genre Catalog Structures)
+// ==========================================
+
+// GenreCategory 类别分组，例如 "主題"、"角色"
+type GenreCategory struct {
+	Name   string     `json:"name"`
+	Genres []Property `json:"genres"`
+}
+
+// GenreCatalog 类别目录，Genres 中的 ID 可直接作为 filterType=genre 的 filterValue (配合相同的 type)
+type GenreCatalog struct {
+	Type       MovieType       `json:"type"`
+	Categories []GenreCategory `json:"categories"`
+}
+
 // ==========================================
 // 分页与响应结构 (Pagination & Responses)
 // ==========================================
@@ -172,6 +195,24 @@ type SearchMoviesPage struct {
 	Keyword    string `json:"keyword"`
 }
 
+// StarMoviesPage 演员信息和演员作品 (一页或全部)
+type StarMoviesPage struct {
+	Star *StarInfo `json:"star"`
+	MoviesPage
+}
+
+// StarsPage 演员列表
+type StarsPage struct {
+	Stars      []Star     `json:"stars"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// SearchStarsPage 演员搜索结果
+type SearchStarsPage struct {
+	StarsPage
+	Keyword string `json:"keyword"`
+}
+
 // ==========================================
 // 请求参数结构 (Request Query)
 // ==========================================
@@ -184,6 +225,20 @@ type GetMoviesQuery struct {
 	FilterValue string     `form:"filterValue" json:"filterValue"`
 }
 
+// GetStarsQuery 演员列表、演员搜索的分页参数
+type GetStarsQuery struct {
+	Page string    `form:"page" json:"page"`
+	Type MovieType `form:"type" json:"type" binding:"omitempty,oneof=normal uncensored"`
+}
+
+// StarMoviesQuery 演员作品查询，All 为 true 时忽略 Page，从第 1 页开始抓取全部作品
+type StarMoviesQuery struct {
+	Page   string     `form:"page" json:"page"`
+	Type   MovieType  `form:"type" json:"type" binding:"omitempty,oneof=normal uncensored"`
+	Magnet MagnetType `form:"magnet" json:"magnet" binding:"omitempty,oneof=all exist"`
+	All    bool       `form:"all" json:"all"`
+}
+
 // javbus访问状态
 type JavbusAccessStatus struct {
 	Access  bool   `json:"access"`
@@ -0,0 +1,172 @@
+package nfo
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// 导出压缩包中的文件名，Kodi / Jellyfin 会自动识别同目录下的这几个文件
+const (
+	NFOFileName    = "movie.nfo"
+	PosterFileName = "poster.jpg"
+	FanartFileName = "fanart.jpg"
+)
+
+// Movie Kodi movie.nfo 结构
+// 参考 https://kodi.wiki/view/NFO_files/Movies
+type Movie struct {
+	XMLName       xml.Name `xml:"movie"`
+	Title         string   `xml:"title"`
+	OriginalTitle string   `xml:"originaltitle,omitempty"`
+	SortTitle     string   `xml:"sorttitle,omitempty"`
+	Premiered     string   `xml:"premiered,omitempty"`
+	Year          string   `xml:"year,omitempty"`
+	Runtime       int      `xml:"runtime,omitempty"`
+	Studios       []string `xml:"studio,omitempty"`
+	Directors     []string `xml:"director,omitempty"`
+	Set           *Set     `xml:"set,omitempty"`
+	Genres        []string `xml:"genre,omitempty"`
+	Actors        []Actor  `xml:"actor,omitempty"`
+	UniqueID      UniqueID `xml:"uniqueid"`
+	Thumbs        []Thumb  `xml:"thumb,omitempty"`
+	Fanart        *Fanart  `xml:"fanart,omitempty"`
+	Tags          []string `xml:"tag,omitempty"`
+}
+
+type Set struct {
+	Name string `xml:"name"`
+}
+
+type Actor struct {
+	Name  string `xml:"name"`
+	Order int    `xml:"order"`
+	Thumb string `xml:"thumb,omitempty"`
+}
+
+type UniqueID struct {
+	Type    string `xml:"type,attr"`
+	Default bool   `xml:"default,attr"`
+	Value   string `xml:",chardata"`
+}
+
+type Thumb struct {
+	Aspect string `xml:"aspect,attr,omitempty"`
+	URL    string `xml:",chardata"`
+}
+
+type Fanart struct {
+	Thumbs []Thumb `xml:"thumb"`
+}
+
+// NewMovie 将影片详情转换为 NFO 结构
+// stars 为演员 ID -> 演员信息，用于填充演员头像，缺失的演员只输出名字
+func NewMovie(detail *model.MovieDetail, stars map[string]*model.StarInfo) *Movie {
+	m := &Movie{
+		Title:         detail.Title,
+		OriginalTitle: detail.Title,
+		SortTitle:     detail.ID,
+		Premiered:     detail.Date,
+		Runtime:       detail.VideoLength,
+		UniqueID:      UniqueID{Type: "javbus", Default: true, Value: detail.ID},
+	}
+
+	if len(detail.Date) >= 4 {
+		if _, err := strconv.Atoi(detail.Date[:4]); err == nil {
+			m.Year = detail.Date[:4]
+		}
+	}
+
+	// 製作商作为 studio，發行商作为第二个 studio (Jellyfin 支持多个)
+	if detail.Producer != nil && detail.Producer.Name != "" {
+		m.Studios = append(m.Studios, detail.Producer.Name)
+	}
+	if detail.Publisher != nil && detail.Publisher.Name != "" &&
+		(detail.Producer == nil || detail.Publisher.Name != detail.Producer.Name) {
+		m.Studios = append(m.Studios, detail.Publisher.Name)
+	}
+	if detail.Director != nil && detail.Director.Name != "" {
+		m.Directors = append(m.Directors, detail.Director.Name)
+	}
+	if detail.Series != nil && detail.Series.Name != "" {
+		m.Set = &Set{Name: detail.Series.Name}
+	}
+
+	for _, g := range detail.Genres {
+		m.Genres = append(m.Genres, g.Name)
+	}
+
+	for i, star := range detail.Stars {
+		actor := Actor{Name: star.Name, Order: i}
+		if info, ok := stars[star.ID]; ok && info != nil {
+			actor.Thumb = info.Avatar
+		}
+		m.Actors = append(m.Actors, actor)
+	}
+
+	if detail.Img != "" {
+		m.Thumbs = []Thumb{{Aspect: "poster", URL: PosterURL(detail.Img)}}
+		m.Fanart = &Fanart{Thumbs: []Thumb{{URL: detail.Img}}}
+	}
+
+	// 番号前缀 (ABP-123 -> ABP) 作为标签，方便在媒体库里归类
+	if idx := strings.Index(detail.ID, "-"); idx > 0 {
+		m.Tags = append(m.Tags, detail.ID[:idx])
+	}
+
+	return m
+}
+
+// Marshal 输出带 XML 头的 movie.nfo 内容
+func Marshal(m *Movie) ([]byte, error) {
+	data, err := xml.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	header := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+	return append([]byte(header), data...), nil
+}
+
+// PosterURL 由封面大图地址推导竖版海报地址
+// JavBus 的封面为 /pics/cover/xxx_b.jpg，对应的竖版缩略图为 /pics/thumb/xxx.jpg
+// 无法推导时原样返回
+func PosterURL(coverURL string) string {
+	if !strings.Contains(coverURL, "/cover/") {
+		return coverURL
+	}
+	poster := strings.Replace(coverURL, "/cover/", "/thumb/", 1)
+	return strings.Replace(poster, "_b.", ".", 1)
+}
+
+// WriteZip 将 NFO 与图片打包写入 w
+// images 为文件名 -> 内容，内容为空的图片会被跳过
+func WriteZip(w io.Writer, nfoData []byte, images map[string][]byte) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create(NFOFileName)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(nfoData); err != nil {
+		return err
+	}
+
+	for name, data := range images {
+		if len(data) == 0 {
+			continue
+		}
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
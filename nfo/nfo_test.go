@@ -0,0 +1,75 @@
+package nfo
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+func TestMarshalMovie(t *testing.T) {
+	detail := &model.MovieDetail{
+		ID:          "SSIS-406",
+		Title:       "SSIS-406 才色兼備な女上司",
+		Img:         "https://www.javbus.com/pics/cover/8xnc_b.jpg",
+		Date:        "2022-05-20",
+		VideoLength: 120,
+		Director:    &model.Property{ID: "hh", Name: "五右衛門"},
+		Producer:    &model.Property{ID: "7q", Name: "エスワン ナンバーワンスタイル"},
+		Publisher:   &model.Property{ID: "9x", Name: "S1 NO.1 STYLE"},
+		Series:      &model.Property{ID: "xx", Name: "週末限定"},
+		Genres:      []model.Property{{ID: "e", Name: "巨乳"}},
+		Stars:       []model.Property{{ID: "2xi", Name: "葵つかさ"}},
+	}
+	stars := map[string]*model.StarInfo{
+		"2xi": {ID: "2xi", Name: "葵つかさ", Avatar: "https://www.javbus.com/pics/actress/2xi_a.jpg"},
+	}
+
+	data, err := Marshal(NewMovie(detail, stars))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`<title>SSIS-406 才色兼備な女上司</title>`,
+		`<premiered>2022-05-20</premiered>`,
+		`<year>2022</year>`,
+		`<runtime>120</runtime>`,
+		`<studio>エスワン ナンバーワンスタイル</studio>`,
+		`<director>五右衛門</director>`,
+		"<set>\n    <name>週末限定</name>",
+		`<genre>巨乳</genre>`,
+		`<thumb>https://www.javbus.com/pics/actress/2xi_a.jpg</thumb>`,
+		`<uniqueid type="javbus" default="true">SSIS-406</uniqueid>`,
+		`<thumb aspect="poster">https://www.javbus.com/pics/thumb/8xnc.jpg</thumb>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("nfo missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteZip(&buf, []byte("<movie/>"), map[string][]byte{
+		PosterFileName: []byte("poster"),
+		FanartFileName: nil, // 下载失败的图片不应出现在压缩包里
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != NFOFileName+","+PosterFileName {
+		t.Errorf("zip entries = %v", names)
+	}
+}
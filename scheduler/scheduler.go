@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Job 定时任务
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// Delay 启动后首次执行前的等待时间
+	Delay time.Duration
+	Run   func(ctx context.Context)
+}
+
+// JobStatus 任务运行状态
+type JobStatus struct {
+	Name      string     `json:"name"`
+	Interval  string     `json:"interval"`
+	Running   bool       `json:"running"`
+	LastRunAt *time.Time `json:"lastRunAt"`
+	LastTook  string     `json:"lastTook,omitempty"`
+	NextRunAt *time.Time `json:"nextRunAt"`
+	RunCount  int64      `json:"runCount"`
+	LastPanic string     `json:"lastPanic,omitempty"`
+}
+
+// Scheduler 简单的间隔调度器，每个任务一个 goroutine，同一任务不会并发执行
+type Scheduler struct {
+	mu     sync.RWMutex
+	jobs   []Job
+	status map[string]*JobStatus
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New 创建调度器
+func New() *Scheduler {
+	return &Scheduler{status: make(map[string]*JobStatus)}
+}
+
+// Add 注册任务，interval <= 0 的任务会被忽略，需要在 Start 之前调用
+func (s *Scheduler) Add(job Job) {
+	if job.Interval <= 0 || job.Run == nil {
+		log.Printf("定时任务 %s 未启用", job.Name)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+	s.status[job.Name] = &JobStatus{Name: job.Name, Interval: job.Interval.String()}
+}
+
+// Start 启动所有任务
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(ctx, job)
+	}
+	log.Printf("定时任务已启动: %d 个", len(s.jobs))
+}
+
+// Stop 停止调度并等待正在执行的任务结束
+func (s *Scheduler) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+	log.Println("定时任务已停止")
+}
+
+// Status 返回所有任务的状态
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		list = append(list, *s.status[job.Name])
+	}
+	return list
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	wait := job.Delay
+	for {
+		next := time.Now().Add(wait)
+		s.update(job.Name, func(st *JobStatus) { st.NextRunAt = &next })
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.run(ctx, job)
+		wait = job.Interval
+	}
+}
+
+// run 执行一次任务，panic 不会导致调度停止
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	start := time.Now()
+	s.update(job.Name, func(st *JobStatus) {
+		st.Running = true
+		st.LastRunAt = &start
+	})
+
+	defer func() {
+		took := time.Since(start)
+		r := recover()
+		if r != nil {
+			log.Printf("定时任务 %s 执行异常: %v", job.Name, r)
+		}
+		s.update(job.Name, func(st *JobStatus) {
+			st.Running = false
+			st.RunCount++
+			st.LastTook = took.Round(time.Millisecond).String()
+			if r != nil {
+				st.LastPanic = fmt.Sprint(r)
+			}
+		})
+	}()
+
+	job.Run(ctx)
+}
+
+func (s *Scheduler) update(name string, fn func(st *JobStatus)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if st, ok := s.status[name]; ok {
+		fn(st)
+	}
+}
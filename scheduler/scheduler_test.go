@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	var runs atomic.Int32
+	s := New()
+	s.Add(Job{
+		Name:     "tick",
+		Interval: 10 * time.Millisecond,
+		Run:      func(ctx context.Context) { runs.Add(1) },
+	})
+	s.Add(Job{
+		Name:     "panic",
+		Interval: 10 * time.Millisecond,
+		Run:      func(ctx context.Context) { panic("boom") },
+	})
+	// 间隔为 0 的任务不注册
+	s.Add(Job{Name: "disabled", Run: func(ctx context.Context) {}})
+
+	s.Start()
+	time.Sleep(55 * time.Millisecond)
+	s.Stop()
+
+	if n := runs.Load(); n < 2 {
+		t.Errorf("tick runs = %d, want >= 2", n)
+	}
+
+	status := s.Status()
+	if len(status) != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+	if status[1].LastPanic != "boom" || status[1].RunCount < 2 {
+		t.Errorf("panic job status = %+v", status[1])
+	}
+}
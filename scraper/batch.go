@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// BatchResult 批量获取影片详情时单个 ID 的结果，Index 为 ID 在去重后列表中的位置
+type BatchResult struct {
+	Index int
+	ID    string
+	Movie *model.MovieDetail
+	Err   error
+}
+
+// BatchIDs 去掉首尾空白、空 ID 和重复的 ID，保持原有顺序
+func BatchIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	list := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		list = append(list, id)
+	}
+	return list
+}
+
+// BatchMovieDetails 用 concurrency 个 worker 并发获取影片详情，每完成一个调用一次 emit (按完成顺序，不会并发调用)
+// 已缓存的影片直接返回，上游请求仍然经过限流；concurrency 超出范围时使用默认值或上限
+// ctx 取消后不再开始新的请求，尚未获取的 ID 以 ctx 的错误返回
+func BatchMovieDetails(ctx context.Context, p Provider, ids []string, concurrency int, emit func(BatchResult)) {
+	if concurrency <= 0 {
+		concurrency = consts.BatchDefaultConcurrency
+	}
+	concurrency = min(concurrency, consts.BatchMaxConcurrency, max(len(ids), 1))
+	bound := WithContext(ctx, p)
+
+	jobs := make(chan int)
+	results := make(chan BatchResult)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := BatchResult{Index: i, ID: ids[i]}
+				if err := ctx.Err(); err != nil {
+					result.Err = err
+				} else {
+					result.Movie, result.Err = bound.GetMovieDetail(ids[i])
+				}
+				results <- result
+			}
+		}()
+	}
+	go func() {
+		for i := range ids {
+			jobs <- i
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	for result := range results {
+		emit(result)
+	}
+}
@@ -0,0 +1,87 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// detailProvider 返回固定的影片详情，MISSING 开头的 ID 返回 ErrNotFound，记录最大并发数
+type detailProvider struct {
+	Provider
+	current, peak atomic.Int32
+}
+
+func (p *detailProvider) GetMovieDetail(id string) (*model.MovieDetail, error) {
+	n := p.current.Add(1)
+	defer p.current.Add(-1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if len(id) >= 7 && id[:7] == "MISSING" {
+		return nil, &UpstreamError{Kind: ErrNotFound, StatusCode: 404}
+	}
+	return &model.MovieDetail{ID: id}, nil
+}
+
+func TestBatchIDs(t *testing.T) {
+	got := BatchIDs([]string{" ABP-123", "", "SSIS-001", "ABP-123 ", "  "})
+	if want := []string{"ABP-123", "SSIS-001"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("BatchIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestBatchMovieDetails(t *testing.T) {
+	ids := []string{"A-1", "A-2", "MISSING-1", "A-3", "A-4", "A-5", "A-6", "A-7"}
+	p := &detailProvider{}
+
+	results := make([]BatchResult, len(ids))
+	var calls int
+	BatchMovieDetails(context.Background(), p, ids, 3, func(r BatchResult) {
+		calls++
+		results[r.Index] = r
+	})
+
+	if calls != len(ids) {
+		t.Fatalf("emit called %d times, want %d", calls, len(ids))
+	}
+	for i, r := range results {
+		if r.ID != ids[i] {
+			t.Errorf("results[%d].ID = %s, want %s", i, r.ID, ids[i])
+		}
+		if r.ID == "MISSING-1" {
+			if !errors.Is(r.Err, ErrNotFound) || r.Movie != nil {
+				t.Errorf("missing movie result = %+v", r)
+			}
+			continue
+		}
+		if r.Err != nil || r.Movie == nil || r.Movie.ID != r.ID {
+			t.Errorf("result = %+v", r)
+		}
+	}
+	if peak := p.peak.Load(); peak > 3 || peak < 2 {
+		t.Errorf("peak concurrency = %d, want 2..3", peak)
+	}
+
+	// ctx 已取消时不再请求，全部返回 ctx 的错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p = &detailProvider{}
+	BatchMovieDetails(ctx, p, ids, 0, func(r BatchResult) {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("cancelled result = %+v", r)
+		}
+	})
+	if p.peak.Load() != 0 {
+		t.Errorf("provider called after cancel")
+	}
+}
@@ -0,0 +1,304 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/metrics"
+	"github.com/fireinrain/javbus-api/model"
+	"golang.org/x/sync/singleflight"
+)
+
+// inflight 进行中的上游请求，key 与缓存 key 相同
+var inflight singleflight.Group
+
+// refreshFailures 后台刷新失败的时间，避免上游不可用时每个请求都触发一次刷新
+var (
+	refreshMu       sync.Mutex
+	refreshFailures = make(map[string]time.Time)
+)
+
+// cacheTTL 缓存有效期
+// 超过 Soft 后数据变为过期 (stale)：依然直接返回，同时在后台刷新；超过 Hard 后数据不可用，需要同步请求上游
+type cacheTTL struct {
+	Soft time.Duration
+	Hard time.Duration
+}
+
+var (
+	// noCache 不读写缓存，只做请求合并
+	noCache = cacheTTL{}
+	// detailTTL 影片详情、演员信息
+	detailTTL = cacheTTL{Soft: consts.PersistCacheExpire, Hard: consts.PersistStaleExpire}
+	// magnetTTL 磁力列表
+	magnetTTL = cacheTTL{Soft: consts.MagnetPersistExpire, Hard: consts.MagnetStaleExpire}
+	// listTTL 影片列表、搜索结果
+	listTTL = cacheTTL{Soft: consts.ListCacheExpire, Hard: consts.ListStaleExpire}
+	// catalogTTL 类别目录
+	catalogTTL = cacheTTL{Soft: consts.CatalogCacheExpire, Hard: consts.CatalogStaleExpire}
+)
+
+// loadCache 先查内存缓存，未命中再查数据库持久化缓存，stale 表示数据已超过软过期时间
+// 数据库命中后回填内存缓存，回填的有效期不会超过数据库中剩余的有效期
+func loadCache[T any](key string) (value T, stale bool, found bool) {
+	if cachedData, stale, found := memCache.Lookup(key); found {
+		if v, ok := cachedData.(T); ok {
+			return v, stale, true
+		}
+	}
+
+	var v T
+	if row, found := cachedb.LoadPersistedEntry(key, &v); found {
+		expiresAt := time.Now().Add(consts.CacheExpire)
+		if row.ExpiresAt.Before(expiresAt) {
+			expiresAt = row.ExpiresAt
+		}
+		memCache.SetWithStale(key, v, row.StaleAt, expiresAt)
+		return v, time.Now().After(row.StaleAt), true
+	}
+
+	var zero T
+	return zero, false, false
+}
+
+// loadOrFetch 先查缓存，未命中时请求上游并写入缓存
+// 同一个 key 的并发请求只会有一个真正请求上游，其余等待并共享同一个结果 (包括错误)
+// 命中过期数据时直接返回，并在后台刷新；上游不可用时会一直返回过期数据，直到超过硬过期时间
+//
+// 上游请求使用发起者的 ctx，ctx 取消后等待中的请求立即返回；
+// 发起者取消导致的失败不会返回给其他仍然有效的请求，它们会重新发起上游请求
+func loadOrFetch[T any](ctx context.Context, key string, ttl cacheTTL, fetch func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	cached := ttl.Hard > 0
+	if cached {
+		if v, stale, found := loadCache[T](key); found {
+			if stale {
+				refreshInBackground(key, ttl, fetch)
+			}
+			return v, nil
+		}
+	}
+
+	for {
+		ch := inflight.DoChan(key, func() (interface{}, error) {
+			// 排队期间上一轮请求可能已经写入了缓存
+			if cached {
+				if v, stale, found := loadCache[T](key); found && !stale {
+					return v, nil
+				}
+			}
+			return fetchAndSave(key, ttl, func() (T, error) {
+				return fetch(ctx)
+			})
+		})
+
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case result := <-ch:
+			if result.Shared {
+				metrics.CoalescedRequestsTotal.WithLabelValues(cachedb.KeyNamespace(key)).Inc()
+			}
+			if result.Err != nil {
+				if errors.Is(result.Err, context.Canceled) && ctx.Err() == nil {
+					continue
+				}
+				return zero, result.Err
+			}
+			return result.Val.(T), nil
+		}
+	}
+}
+
+func fetchAndSave[T any](key string, ttl cacheTTL, fetch func() (T, error)) (interface{}, error) {
+	v, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	if ttl.Hard > 0 {
+		saveCache(key, v, ttl)
+	}
+	return v, nil
+}
+
+// refreshInBackground 在后台刷新过期数据，与同一个 key 的其他请求合并
+// 刷新失败时保留原有数据，并在 CacheRefreshBackoff 内不再重试
+// 刷新不使用触发它的请求的 ctx，请求结束后刷新仍然继续
+func refreshInBackground[T any](key string, ttl cacheTTL, fetch func(ctx context.Context) (T, error)) {
+	refreshMu.Lock()
+	failedAt, failed := refreshFailures[key]
+	refreshMu.Unlock()
+	if failed && time.Since(failedAt) < consts.CacheRefreshBackoff {
+		return
+	}
+
+	ch := inflight.DoChan(key, func() (interface{}, error) {
+		return fetchAndSave(key, ttl, func() (T, error) {
+			return fetch(context.Background())
+		})
+	})
+	go func() {
+		result := <-ch
+		if result.Shared {
+			return
+		}
+		namespace := cachedb.KeyNamespace(key)
+		refreshMu.Lock()
+		defer refreshMu.Unlock()
+		if result.Err != nil {
+			refreshFailures[key] = time.Now()
+			metrics.CacheRefreshTotal.WithLabelValues(namespace, "error").Inc()
+			log.Printf("后台刷新缓存失败 %s，继续使用过期数据: %v", key, result.Err)
+			return
+		}
+		delete(refreshFailures, key)
+		metrics.CacheRefreshTotal.WithLabelValues(namespace, "ok").Inc()
+	}()
+}
+
+// ConfigureCache 根据配置设置内存缓存的上限
+func ConfigureCache(cfg config.CacheConfig) {
+	limits := cachedb.CacheLimits{
+		CacheLimit: cacheLimit(cfg.CacheLimitConfig),
+		Namespaces: make(map[string]cachedb.CacheLimit, len(cfg.Namespaces)),
+	}
+	for name, limit := range cfg.Namespaces {
+		limits.Namespaces[name] = cacheLimit(limit)
+	}
+	memCache.SetLimits(limits)
+}
+
+func cacheLimit(cfg config.CacheLimitConfig) cachedb.CacheLimit {
+	return cachedb.CacheLimit{MaxEntries: cfg.MaxEntries, MaxBytes: int64(cfg.MaxMemoryMB) << 20}
+}
+
+// CacheStats 内存缓存的条目数、估算内存和淘汰次数
+func CacheStats() cachedb.CacheStats {
+	return memCache.Stats()
+}
+
+// IsStale 缓存中 key 对应的数据是否已过期 (上游不可用时返回的过期数据)
+func IsStale(key string) bool {
+	return memCache.IsStale(key)
+}
+
+// CacheInfo 内存缓存中 key 的写入时间和过期时间，用于设置响应的缓存头
+func CacheInfo(key string) (cachedb.EntryInfo, bool) {
+	info, _, found := memCache.Entry(key)
+	return info, found
+}
+
+// saveCache 同时写入内存缓存和数据库持久化缓存
+// 持久化失败只记录日志，不影响本次请求
+func saveCache(key string, value interface{}, ttl cacheTTL) {
+	now := time.Now()
+	expiresAt := now.Add(consts.CacheExpire)
+	if ttl.Hard < consts.CacheExpire {
+		expiresAt = now.Add(ttl.Hard)
+	}
+	memCache.SetWithStale(key, value, now.Add(ttl.Soft), expiresAt)
+	if err := cachedb.SavePersistedStale(key, value, ttl.Soft, ttl.Hard); err != nil {
+		log.Printf("写入持久化缓存失败 %s: %v", key, err)
+	}
+}
+
+// deleteCache 同时删除内存缓存和数据库持久化缓存
+func deleteCache(key string) {
+	memCache.Delete(key)
+	if err := cachedb.DeletePersisted(key); err != nil {
+		log.Printf("删除持久化缓存失败 %s: %v", key, err)
+	}
+}
+
+// MovieCacheKey 影片详情缓存的 key
+func MovieCacheKey(id string) string {
+	return "movie:" + id
+}
+
+// StarCacheKey 演员信息缓存的 key，type 为空与 normal 请求的是同一页面，共用一个 key
+func StarCacheKey(starId, movieType string) string {
+	return "star:" + normalizeType(model.MovieType(movieType)) + ":" + starId
+}
+
+// StarMoviesCacheKey 演员作品某一页的 key
+func StarMoviesCacheKey(starId string, q *model.StarMoviesQuery) string {
+	return "starmovies:" + normalizeType(q.Type) + ":" + normalizeMagnet(q.Magnet) + ":" + normalizePage(q.Page) + ":" + starId
+}
+
+// StarsCacheKey 演员列表的 key
+func StarsCacheKey(q *model.GetStarsQuery) string {
+	return "stars:" + normalizeType(q.Type) + ":" + normalizePage(q.Page)
+}
+
+// StarSearchCacheKey 演员搜索结果的 key
+func StarSearchCacheKey(keyword string, q *model.GetStarsQuery) string {
+	return "starsearch:" + normalizeType(q.Type) + ":" + normalizePage(q.Page) + ":" + strings.TrimSpace(keyword)
+}
+
+// GenresCacheKey 类别目录缓存的 key
+func GenresCacheKey(movieType model.MovieType) string {
+	return "genres:" + normalizeType(movieType)
+}
+
+// MoviesCacheKey 影片列表的 key，由类型、筛选条件、磁力过滤和页码组成
+// 请求上游时等价的参数 (type 为空与 normal、page 为空与 1 等) 使用同一个 key
+func MoviesCacheKey(q *model.GetMoviesQuery) string {
+	filterValue := ""
+	if q.FilterType != "" {
+		filterValue = q.FilterValue
+	}
+	return "movies:" + normalizeType(q.Type) + ":" + string(q.FilterType) + ":" + filterValue + ":" + normalizeMagnet(q.Magnet) + ":" + normalizePage(q.Page)
+}
+
+// SearchCacheKey 搜索结果的 key
+func SearchCacheKey(keyword string, q *model.GetMoviesQuery) string {
+	return "search:" + normalizeType(q.Type) + ":" + normalizeMagnet(q.Magnet) + ":" + normalizePage(q.Page) + ":" + strings.TrimSpace(keyword)
+}
+
+// normalizeType 类型为空时视为 normal
+func normalizeType(t model.MovieType) string {
+	if t == "" {
+		return string(model.MovieTypeNormal)
+	}
+	return string(t)
+}
+
+// normalizeMagnet 只有 exist 会过滤无磁力的影片，其余都视为 all
+func normalizeMagnet(m model.MagnetType) string {
+	if m == model.MagnetTypeExist {
+		return string(model.MagnetTypeExist)
+	}
+	return string(model.MagnetTypeAll)
+}
+
+// normalizePage 页码为空或不合法时视为第 1 页
+func normalizePage(page string) string {
+	if n, err := strconv.Atoi(page); err == nil && n > 1 {
+		return strconv.Itoa(n)
+	}
+	return "1"
+}
+
+// MagnetCacheKey 磁力链接缓存的 key
+func MagnetCacheKey(movieId, gid, uc, sortBy, sortOrder string) string {
+	return MagnetCachePrefix(movieId) + gid + ":" + uc + ":" + sortBy + ":" + sortOrder
+}
+
+// MagnetCachePrefix 某部影片全部磁力链接缓存的 key 前缀
+func MagnetCachePrefix(movieId string) string {
+	return "mag:" + movieId + ":"
+}
+
+// InvalidateMagnets 删除某部影片磁力链接的缓存，下次查询时重新请求
+// 用于磁力监控等需要拿到最新数据的场景
+func InvalidateMagnets(movieId, gid, uc, sortBy, sortOrder string) {
+	deleteCache(MagnetCacheKey(movieId, gid, uc, sortBy, sortOrder))
+}
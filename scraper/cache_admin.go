@@ -0,0 +1,192 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/model"
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheLayer 缓存项在某一层 (内存 / 数据库) 中的状态
+type CacheLayer struct {
+	CreatedAt  time.Time `json:"createdAt"`
+	StaleAt    time.Time `json:"staleAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	AgeSeconds int64     `json:"ageSeconds"`
+	TTLSeconds int64     `json:"ttlSeconds"` // 距离硬过期的剩余秒数
+	Stale      bool      `json:"stale"`
+	Size       int64     `json:"size,omitempty"` // 仅内存缓存，估算值
+}
+
+// CacheEntry 管理接口返回的缓存项，不在某一层中时对应字段为空
+type CacheEntry struct {
+	Key       string          `json:"key"`
+	Namespace string          `json:"namespace"`
+	Memory    *CacheLayer     `json:"memory,omitempty"`
+	Database  *CacheLayer     `json:"database,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+}
+
+// CachePurgeResult 删除的缓存数量
+type CachePurgeResult struct {
+	Memory   int   `json:"memory"`
+	Database int64 `json:"database"`
+}
+
+func newCacheLayer(createdAt, staleAt, expiresAt time.Time, size int64) *CacheLayer {
+	now := time.Now()
+	layer := &CacheLayer{
+		CreatedAt:  createdAt,
+		StaleAt:    staleAt,
+		ExpiresAt:  expiresAt,
+		AgeSeconds: int64(now.Sub(createdAt).Seconds()),
+		Stale:      !staleAt.IsZero() && now.After(staleAt),
+		Size:       size,
+	}
+	if !expiresAt.IsZero() {
+		layer.TTLSeconds = int64(expiresAt.Sub(now).Seconds())
+	}
+	return layer
+}
+
+func memoryLayer(info cachedb.EntryInfo) *CacheLayer {
+	return newCacheLayer(info.CreatedAt, info.StaleAt, info.ExpiresAt, info.Size)
+}
+
+func databaseLayer(row *cachedb.ScrapeCache) *CacheLayer {
+	return newCacheLayer(row.UpdatedAt, row.StaleAt, row.ExpiresAt, 0)
+}
+
+// ListCacheEntries 列出以 prefix 开头的缓存项 (合并内存和数据库)，按 key 排序
+// 结果超过 limit 时截断并返回 truncated = true
+func ListCacheEntries(prefix string, limit int) ([]CacheEntry, bool, error) {
+	entries := make(map[string]*CacheEntry)
+	entry := func(key string) *CacheEntry {
+		if e, ok := entries[key]; ok {
+			return e
+		}
+		e := &CacheEntry{Key: key, Namespace: cachedb.KeyNamespace(key)}
+		entries[key] = e
+		return e
+	}
+
+	for _, info := range memCache.Keys(prefix) {
+		entry(info.Key).Memory = memoryLayer(info)
+	}
+	rows, err := cachedb.ListPersisted(prefix, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	for i := range rows {
+		entry(rows[i].CacheKey).Database = databaseLayer(&rows[i])
+	}
+
+	list := make([]CacheEntry, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	if len(list) > limit {
+		return list[:limit], true, nil
+	}
+	return list, false, nil
+}
+
+// InspectCacheEntry 查看单个缓存项的时间信息和值，两层都不存在时返回 nil
+func InspectCacheEntry(key string) (*CacheEntry, error) {
+	e := &CacheEntry{Key: key, Namespace: cachedb.KeyNamespace(key)}
+
+	if info, value, found := memCache.Entry(key); found {
+		e.Memory = memoryLayer(info)
+		if data, err := json.Marshal(value); err == nil {
+			e.Value = data
+		}
+	}
+
+	row, err := cachedb.GetPersisted(key)
+	if err != nil {
+		return nil, err
+	}
+	// 已过期但尚未清理的数据不会再被读取，视为不存在
+	if row != nil && row.ExpiresAt.After(time.Now()) {
+		e.Database = databaseLayer(row)
+		if e.Value == nil {
+			e.Value = json.RawMessage(row.Data)
+		}
+	}
+
+	if e.Memory == nil && e.Database == nil {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// DeleteCacheKey 删除单个缓存项 (内存和数据库)
+func DeleteCacheKey(key string) error {
+	memCache.Delete(key)
+	clearRefreshFailures(key)
+	return cachedb.DeletePersisted(key)
+}
+
+// DeleteCachePrefix 删除所有以 prefix 开头的缓存项，例如 "mag:ABP-123:" 删除该影片的全部磁力缓存
+func DeleteCachePrefix(prefix string) (CachePurgeResult, error) {
+	result := CachePurgeResult{Memory: memCache.DeletePrefix(prefix)}
+	clearRefreshFailures(prefix)
+	n, err := cachedb.DeletePersistedPrefix(prefix)
+	result.Database = n
+	return result, err
+}
+
+// FlushCache 清空全部缓存
+func FlushCache() (CachePurgeResult, error) {
+	return DeleteCachePrefix("")
+}
+
+// clearRefreshFailures 删除缓存后同时清除后台刷新的失败记录，下次过期时立即重试
+func clearRefreshFailures(prefix string) {
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+	for key := range refreshFailures {
+		if strings.HasPrefix(key, prefix) {
+			delete(refreshFailures, key)
+		}
+	}
+}
+
+// RefreshMovieDetail 强制从上游重新获取影片详情并覆盖缓存，同时删除该影片的磁力缓存
+// 上游请求失败时保留原有缓存
+// 只与同一影片的其他强制刷新合并 (不会拿到普通请求读到的缓存)，其他发起者取消导致的失败会重新请求
+func (s *JavbusScraper) RefreshMovieDetail(ctx context.Context, id string) (*model.MovieDetail, error) {
+	key := MovieCacheKey(id)
+	var result singleflight.Result
+	for {
+		ch := inflight.DoChan("refresh:"+key, func() (interface{}, error) {
+			return fetchAndSave(key, detailTTL, func() (*model.MovieDetail, error) {
+				return s.fetchMovieDetail(ctx, id)
+			})
+		})
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case result = <-ch:
+		}
+		if errors.Is(result.Err, context.Canceled) && ctx.Err() == nil {
+			continue
+		}
+		break
+	}
+	if result.Err != nil {
+		return nil, result.Err
+	}
+	clearRefreshFailures(key)
+	if _, err := DeleteCachePrefix(MagnetCachePrefix(id)); err != nil {
+		return nil, err
+	}
+	return result.Val.(*model.MovieDetail), nil
+}
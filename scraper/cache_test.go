@@ -0,0 +1,254 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+func TestLoadOrFetchCoalesces(t *testing.T) {
+	key := MovieCacheKey("TEST-COALESCE")
+	t.Cleanup(func() { memCache.Delete(key) })
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func(context.Context) (*model.MovieDetail, error) {
+		calls.Add(1)
+		<-release
+		return &model.MovieDetail{ID: "TEST-COALESCE"}, nil
+	}
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]*model.MovieDetail, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			detail, err := loadOrFetch(context.Background(), key, detailTTL, fetch)
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = detail
+		}(i)
+	}
+	// 等待所有调用进入等待状态后再放行上游请求
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("fetch called %d times, want 1", calls.Load())
+	}
+	for i, detail := range results {
+		if detail != results[0] {
+			t.Fatalf("result %d not shared: %p != %p", i, detail, results[0])
+		}
+	}
+
+	// 之后的请求直接命中缓存
+	if _, err := loadOrFetch(context.Background(), key, detailTTL, fetch); err != nil || calls.Load() != 1 {
+		t.Errorf("cached call: calls = %d, err = %v", calls.Load(), err)
+	}
+}
+
+func TestLoadOrFetchError(t *testing.T) {
+	q := &model.GetMoviesQuery{Page: "2"}
+	var calls int
+	fetch := func(context.Context) (*model.MoviesPage, error) {
+		calls++
+		return nil, errors.New("request failed with status code: 503")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := loadOrFetch(context.Background(), MoviesCacheKey(q), noCache, fetch); err == nil {
+			t.Fatal("expected error")
+		}
+	}
+	// 错误不会被缓存
+	if calls != 2 {
+		t.Errorf("fetch called %d times, want 2", calls)
+	}
+	if MoviesCacheKey(&model.GetMoviesQuery{}) != MoviesCacheKey(&model.GetMoviesQuery{Page: "1"}) {
+		t.Error("empty page and page 1 should share a key")
+	}
+}
+
+func TestLoadOrFetchStaleWhileRevalidate(t *testing.T) {
+	key := MovieCacheKey("TEST-STALE")
+	failing := MovieCacheKey("TEST-STALE-DOWN")
+	t.Cleanup(func() {
+		memCache.Delete(key)
+		memCache.Delete(failing)
+	})
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+
+	// 过期数据立即返回，后台刷新成功后变为新数据
+	memCache.SetWithStale(key, &model.MovieDetail{ID: "old"}, past, future)
+	refreshed := make(chan struct{})
+	detail, err := loadOrFetch(context.Background(), key, detailTTL, func(context.Context) (*model.MovieDetail, error) {
+		defer close(refreshed)
+		return &model.MovieDetail{ID: "new"}, nil
+	})
+	if err != nil || detail.ID != "old" {
+		t.Fatalf("stale load = %+v, %v", detail, err)
+	}
+	<-refreshed
+	waitFor(t, func() bool { return !IsStale(key) })
+	if detail, _ := loadOrFetch[*model.MovieDetail](context.Background(), key, detailTTL, nil); detail.ID != "new" {
+		t.Errorf("after refresh = %+v", detail)
+	}
+
+	// 上游不可用时继续返回过期数据，并在退避时间内不再重试
+	memCache.SetWithStale(failing, &model.MovieDetail{ID: "old"}, past, future)
+	var calls atomic.Int32
+	fetch := func(context.Context) (*model.MovieDetail, error) {
+		calls.Add(1)
+		return nil, errors.New("request failed with status code: 503")
+	}
+	for i := 0; i < 3; i++ {
+		detail, err := loadOrFetch(context.Background(), failing, detailTTL, fetch)
+		if err != nil || detail.ID != "old" || !IsStale(failing) {
+			t.Fatalf("load %d = %+v, %v", i, detail, err)
+		}
+		waitFor(t, func() bool {
+			refreshMu.Lock()
+			defer refreshMu.Unlock()
+			_, failed := refreshFailures[failing]
+			return failed
+		})
+	}
+	if calls.Load() != 1 {
+		t.Errorf("refresh attempts = %d, want 1", calls.Load())
+	}
+
+	// 超过硬过期时间后同步请求上游
+	memCache.SetWithStale(failing, &model.MovieDetail{ID: "old"}, past, past)
+	if _, err := loadOrFetch(context.Background(), failing, detailTTL, fetch); err == nil {
+		t.Error("expired entry should not be served")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheAdmin(t *testing.T) {
+	t.Cleanup(func() { memCache.DeletePrefix("mag:TEST-ADMIN") })
+	memCache.Set(MagnetCacheKey("TEST-ADMIN", "1", "0", "", ""), []model.Magnet{{ID: "a"}}, time.Hour)
+	memCache.Set(MagnetCacheKey("TEST-ADMIN", "2", "0", "", ""), []model.Magnet{{ID: "b"}}, time.Hour)
+	memCache.Set(MagnetCacheKey("TEST-ADMIN2", "1", "0", "", ""), []model.Magnet{{ID: "c"}}, time.Hour)
+
+	entries, truncated, err := ListCacheEntries(MagnetCachePrefix("TEST-ADMIN"), 1)
+	if err != nil || len(entries) != 1 || !truncated || entries[0].Memory == nil || entries[0].Database != nil {
+		t.Fatalf("ListCacheEntries() = %+v, %v, %v", entries, truncated, err)
+	}
+
+	entry, err := InspectCacheEntry(MagnetCacheKey("TEST-ADMIN", "1", "0", "", ""))
+	if err != nil || entry == nil || entry.Memory.TTLSeconds <= 0 || string(entry.Value) == "" {
+		t.Fatalf("InspectCacheEntry() = %+v, %v", entry, err)
+	}
+
+	result, err := DeleteCachePrefix(MagnetCachePrefix("TEST-ADMIN"))
+	if err != nil || result.Memory != 2 {
+		t.Errorf("DeleteCachePrefix() = %+v, %v", result, err)
+	}
+	if entry, _ := InspectCacheEntry(MagnetCacheKey("TEST-ADMIN2", "1", "0", "", "")); entry == nil {
+		t.Error("other movie's magnets should be kept")
+	}
+}
+
+func TestListCacheKeys(t *testing.T) {
+	same := [][2]*model.GetMoviesQuery{
+		{{}, {Page: "1", Type: model.MovieTypeNormal, Magnet: model.MagnetTypeAll}},
+		{{FilterValue: "ignored"}, {}},
+		{{Page: "0"}, {Page: "abc"}},
+	}
+	for _, pair := range same {
+		if MoviesCacheKey(pair[0]) != MoviesCacheKey(pair[1]) {
+			t.Errorf("MoviesCacheKey(%+v) != MoviesCacheKey(%+v)", pair[0], pair[1])
+		}
+	}
+
+	base := &model.GetMoviesQuery{FilterType: model.FilterTypeStar, FilterValue: "okq"}
+	different := []*model.GetMoviesQuery{
+		{FilterType: model.FilterTypeStar, FilterValue: "okq", Page: "2"},
+		{FilterType: model.FilterTypeStar, FilterValue: "okq", Magnet: model.MagnetTypeExist},
+		{FilterType: model.FilterTypeStar, FilterValue: "okq", Type: model.MovieTypeUncensored},
+		{FilterType: model.FilterTypeStar, FilterValue: "abc"},
+	}
+	for _, q := range different {
+		if MoviesCacheKey(q) == MoviesCacheKey(base) {
+			t.Errorf("MoviesCacheKey(%+v) should differ from %s", q, MoviesCacheKey(base))
+		}
+	}
+
+	if SearchCacheKey(" ABP ", &model.GetMoviesQuery{}) != SearchCacheKey("ABP", &model.GetMoviesQuery{Type: model.MovieTypeNormal, Page: "1"}) {
+		t.Error("search keys should ignore surrounding spaces and defaults")
+	}
+	if StarCacheKey("okq", "") != StarCacheKey("okq", string(model.MovieTypeNormal)) || StarCacheKey("okq", "") == StarCacheKey("okq", string(model.MovieTypeUncensored)) {
+		t.Error("star keys should treat an empty type as normal")
+	}
+	if listTTL.Soft >= detailTTL.Soft {
+		t.Error("list pages should expire sooner than details")
+	}
+}
+
+func TestLoadOrFetchContext(t *testing.T) {
+	key := MovieCacheKey("TEST-CONTEXT")
+	t.Cleanup(func() { memCache.Delete(key) })
+
+	var calls atomic.Int32
+	fetch := func(ctx context.Context) (*model.MovieDetail, error) {
+		if calls.Add(1) == 1 {
+			// 第一次请求一直等到发起者取消
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return &model.MovieDetail{ID: "TEST-CONTEXT"}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := loadOrFetch(ctx, key, detailTTL, fetch)
+		first <- err
+	}()
+	waitFor(t, func() bool { return calls.Load() == 1 })
+
+	// 等待中的请求取消后立即返回
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer waitCancel()
+	if _, err := loadOrFetch(waitCtx, key, detailTTL, fetch); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("waiter err = %v, want deadline exceeded", err)
+	}
+
+	// 发起者取消后，仍然有效的请求重新发起并拿到结果
+	second := make(chan *model.MovieDetail, 1)
+	go func() {
+		detail, _ := loadOrFetch(context.Background(), key, detailTTL, fetch)
+		second <- detail
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("initiator err = %v, want canceled", err)
+	}
+	if detail := <-second; detail == nil || detail.ID != "TEST-CONTEXT" {
+		t.Errorf("second caller = %+v", detail)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("fetch called %d times, want 2", calls.Load())
+	}
+}
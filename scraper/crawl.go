@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// CrawlOptions 多页抓取参数，超出范围的值按默认值或上限处理
+type CrawlOptions struct {
+	MaxPages    int // 最多抓取的页数，默认 consts.CrawlDefaultPages，上限 consts.CrawlMaxPages
+	Concurrency int // 同时请求的页数，默认 consts.CrawlDefaultConcurrency，上限 consts.CrawlMaxConcurrency
+}
+
+// CrawlPage 抓取到的一页
+type CrawlPage struct {
+	Page        int           `json:"page"`
+	Movies      []model.Movie `json:"movies"`
+	HasNextPage bool          `json:"hasNextPage"`
+}
+
+// CrawlResult 抓取结束时的统计，Truncated 表示因达到 MaxPages 而停止
+type CrawlResult struct {
+	Pages     int  `json:"pages"`
+	Movies    int  `json:"movies"`
+	Truncated bool `json:"truncated"`
+}
+
+func (o CrawlOptions) normalize() CrawlOptions {
+	if o.MaxPages <= 0 {
+		o.MaxPages = consts.CrawlDefaultPages
+	}
+	o.MaxPages = min(o.MaxPages, consts.CrawlMaxPages)
+	if o.Concurrency <= 0 {
+		o.Concurrency = consts.CrawlDefaultConcurrency
+	}
+	o.Concurrency = min(o.Concurrency, consts.CrawlMaxConcurrency)
+	return o
+}
+
+// CrawlMovies 从 q.Page 开始逐页抓取影片列表 (keyword 不为空时抓取搜索结果)，直到没有下一页或达到 MaxPages
+// 每一批最多同时请求 Concurrency 页，只请求分页中已经出现过的页码，不会越过最后一页；
+// 每页按页码顺序交给 emit，emit 返回错误或 ctx 取消时停止抓取
+// 搜索没有结果 (ErrNotFound) 时视为空结果
+func CrawlMovies(ctx context.Context, p Provider, keyword string, q *model.GetMoviesQuery, opts CrawlOptions, emit func(CrawlPage) error) (CrawlResult, error) {
+	opts = opts.normalize()
+	keyword = strings.TrimSpace(keyword)
+	first, _ := strconv.Atoi(normalizePage(q.Page))
+	last := first + opts.MaxPages - 1
+
+	var result CrawlResult
+	next, known := first, first
+	for next <= known && next <= last {
+		end := min(known, last, next+opts.Concurrency-1)
+		pages, err := crawlBatch(ctx, p, keyword, q, next, end)
+		if err != nil {
+			if next == first && keyword != "" && errors.Is(err, ErrNotFound) {
+				return result, nil
+			}
+			return result, err
+		}
+
+		for _, page := range pages {
+			// 已知的最大页码: 分页中列出的页码，以及有下一页时的下一页
+			for _, n := range page.Pagination.Pages {
+				known = max(known, n)
+			}
+			if page.Pagination.HasNextPage {
+				known = max(known, page.n+1)
+			}
+
+			crawled := CrawlPage{Page: page.n, Movies: page.Movies, HasNextPage: page.Pagination.HasNextPage}
+			if crawled.Movies == nil {
+				crawled.Movies = []model.Movie{}
+			}
+			if err := emit(crawled); err != nil {
+				return result, err
+			}
+			result.Pages++
+			result.Movies += len(page.Movies)
+
+			if !page.Pagination.HasNextPage {
+				return result, nil
+			}
+		}
+		next = end + 1
+	}
+	result.Truncated = next > last
+	return result, nil
+}
+
+type crawledPage struct {
+	n int
+	*model.MoviesPage
+}
+
+// crawlBatch 并发请求 [from, to] 之间的页，按页码顺序返回，任意一页失败时返回该错误
+func crawlBatch(ctx context.Context, p Provider, keyword string, q *model.GetMoviesQuery, from, to int) ([]crawledPage, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pages := make([]crawledPage, to-from+1)
+	errs := make([]error, len(pages))
+	var wg sync.WaitGroup
+	for i := range pages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pageQuery := *q
+			pageQuery.Page = strconv.Itoa(from + i)
+			page, err := fetchCrawlPage(ctx, p, keyword, &pageQuery)
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			pages[i] = crawledPage{n: from + i, MoviesPage: page}
+		}()
+	}
+	wg.Wait()
+
+	// 优先返回最先失败的页的错误，而不是被它取消的其他页的 context.Canceled
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+func fetchCrawlPage(ctx context.Context, p Provider, keyword string, q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	bound := WithContext(ctx, p)
+	if keyword == "" {
+		return bound.GetMoviesByPage(q)
+	}
+	page, err := bound.GetMoviesByKeywordAndPage(keyword, q)
+	if err != nil {
+		return nil, err
+	}
+	return &page.MoviesPage, nil
+}
@@ -0,0 +1,129 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// pagedProvider 共 total 页，分页只列出当前页前后 2 页 (与 JavBus 一样看不到总页数)
+type pagedProvider struct {
+	Provider
+	total   int
+	failAt  int // 请求该页时返回 ErrUpstream
+	keyword string
+
+	mu        sync.Mutex
+	requested []int
+}
+
+func (p *pagedProvider) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	n, _ := strconv.Atoi(q.Page)
+	p.mu.Lock()
+	p.requested = append(p.requested, n)
+	p.mu.Unlock()
+	if n == p.failAt {
+		return nil, &UpstreamError{Kind: ErrUpstream, StatusCode: 500}
+	}
+	if n > p.total {
+		return nil, &UpstreamError{Kind: ErrNotFound, StatusCode: 404}
+	}
+
+	page := &model.MoviesPage{Pagination: model.Pagination{CurrentPage: n, HasNextPage: n < p.total}}
+	for i := max(1, n-2); i <= min(p.total, n+2); i++ {
+		page.Pagination.Pages = append(page.Pagination.Pages, i)
+	}
+	for i := 1; i <= 2; i++ {
+		page.Movies = append(page.Movies, model.Movie{ID: fmt.Sprintf("P%d-%d", n, i)})
+	}
+	return page, nil
+}
+
+func (p *pagedProvider) GetMoviesByKeywordAndPage(keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
+	if keyword != p.keyword {
+		return nil, &UpstreamError{Kind: ErrNotFound, StatusCode: 404}
+	}
+	page, err := p.GetMoviesByPage(q)
+	if err != nil {
+		return nil, err
+	}
+	return &model.SearchMoviesPage{MoviesPage: *page, Keyword: keyword}, nil
+}
+
+func crawlPages(t *testing.T, p Provider, keyword string, q *model.GetMoviesQuery, opts CrawlOptions) ([]int, CrawlResult, error) {
+	t.Helper()
+	var pages []int
+	result, err := CrawlMovies(context.Background(), p, keyword, q, opts, func(page CrawlPage) error {
+		pages = append(pages, page.Page)
+		if len(page.Movies) != 2 || page.Movies[0].ID != fmt.Sprintf("P%d-1", page.Page) {
+			t.Errorf("page %d movies = %+v", page.Page, page.Movies)
+		}
+		return nil
+	})
+	return pages, result, err
+}
+
+func TestCrawlMovies(t *testing.T) {
+	// 全部抓取，按顺序输出，不请求最后一页之后的页
+	p := &pagedProvider{total: 7}
+	pages, result, err := crawlPages(t, p, "", &model.GetMoviesQuery{}, CrawlOptions{Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4, 5, 6, 7}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %v, want %v", pages, want)
+	}
+	if result != (CrawlResult{Pages: 7, Movies: 14}) {
+		t.Errorf("result = %+v", result)
+	}
+	for _, n := range p.requested {
+		if n > 7 {
+			t.Errorf("requested page %d beyond the last page", n)
+		}
+	}
+
+	// 从第 3 页开始，最多 3 页
+	pages, result, err = crawlPages(t, &pagedProvider{total: 20}, "", &model.GetMoviesQuery{Page: "3"}, CrawlOptions{MaxPages: 3, Concurrency: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{3, 4, 5}; !reflect.DeepEqual(pages, want) || !result.Truncated {
+		t.Errorf("pages = %v, result = %+v, want %v truncated", pages, result, want)
+	}
+
+	// 搜索没有结果时为空结果
+	pages, result, err = crawlPages(t, &pagedProvider{total: 3, keyword: "abc"}, "nothing", &model.GetMoviesQuery{}, CrawlOptions{})
+	if err != nil || len(pages) != 0 || result.Pages != 0 {
+		t.Errorf("empty search: pages = %v, result = %+v, err = %v", pages, result, err)
+	}
+	pages, _, err = crawlPages(t, &pagedProvider{total: 3, keyword: "abc"}, " abc ", &model.GetMoviesQuery{}, CrawlOptions{})
+	if err != nil || len(pages) != 3 {
+		t.Errorf("search: pages = %v, err = %v", pages, err)
+	}
+
+	// 中途失败时已输出的页保留，返回该页的错误
+	pages, _, err = crawlPages(t, &pagedProvider{total: 10, failAt: 4}, "", &model.GetMoviesQuery{}, CrawlOptions{Concurrency: 2})
+	if !errors.Is(err, ErrUpstream) {
+		t.Errorf("err = %v, want ErrUpstream", err)
+	}
+	if want := []int{1, 2, 3}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages before failure = %v, want %v", pages, want)
+	}
+
+	// emit 出错时停止
+	stop := errors.New("client gone")
+	calls := 0
+	_, err = CrawlMovies(context.Background(), &pagedProvider{total: 10}, "", &model.GetMoviesQuery{}, CrawlOptions{}, func(CrawlPage) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("emit error: err = %v, calls = %d", err, calls)
+	}
+}
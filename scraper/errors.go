@@ -0,0 +1,125 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// 上游错误的种类，使用 errors.Is 判断
+var (
+	// ErrNotFound 影片、演员等不存在 (上游返回 404)
+	ErrNotFound = errors.New("not found")
+	// ErrBlocked 上游拒绝访问: 403、Cloudflare 验证页等
+	ErrBlocked = errors.New("upstream blocked")
+	// ErrUpstreamTimeout 请求上游超时
+	ErrUpstreamTimeout = errors.New("upstream timeout")
+	// ErrParse 页面结构无法解析，通常是站点改版或返回了非预期的页面
+	ErrParse = errors.New("parse failed")
+	// ErrRateLimited 上游返回 429，或该域名因 403/429/503 处于客户端退避期
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUpstream 其他上游错误 (5xx、连接失败等)
+	ErrUpstream = errors.New("upstream error")
+)
+
+// UpstreamError 请求上游失败的详细信息，Kind 为上面的错误种类之一
+type UpstreamError struct {
+	Kind       error
+	URL        string
+	StatusCode int           // 没有收到响应时为 0
+	RetryAfter time.Duration // 上游返回的 Retry-After，没有时为 0
+	Err        error         // 底层错误，可能为空
+}
+
+func (e *UpstreamError) Error() string {
+	msg := e.Kind.Error()
+	switch {
+	case e.Err != nil:
+		msg += ": " + e.Err.Error()
+	case e.StatusCode > 0:
+		msg += fmt.Sprintf(": request failed with status code: %d", e.StatusCode)
+	}
+	if e.URL != "" {
+		msg += " (" + e.URL + ")"
+	}
+	return msg
+}
+
+// Unwrap 同时支持 errors.Is(err, ErrNotFound) 和判断底层错误
+func (e *UpstreamError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Err}
+}
+
+// upstreamError 把请求结果转为带类型的错误，请求成功 (200) 时返回 nil
+// 客户端取消 (context.Canceled) 原样返回，不属于上游错误
+func upstreamError(resp *resty.Response, url string, err error) error {
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		e := &UpstreamError{Kind: ErrUpstream, URL: url, Err: err}
+		var netErr net.Error
+		var backoff *BackoffError
+		switch {
+		case errors.As(err, &backoff):
+			e.Kind = ErrRateLimited
+			e.RetryAfter = time.Until(backoff.Until)
+		case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+			e.Kind = ErrUpstreamTimeout
+		case errors.Is(err, ErrCloudflareChallenge):
+			e.Kind = ErrBlocked
+		}
+		if resp != nil {
+			e.StatusCode = resp.StatusCode()
+		}
+		return e
+	}
+
+	code := resp.StatusCode()
+	if code == http.StatusOK {
+		return nil
+	}
+	e := &UpstreamError{Kind: ErrUpstream, URL: url, StatusCode: code}
+	switch code {
+	case http.StatusNotFound, http.StatusGone:
+		e.Kind = ErrNotFound
+	case http.StatusForbidden:
+		e.Kind = ErrBlocked
+	case http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+		e.RetryAfter = retryAfter(resp.Header().Get("Retry-After"))
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout, 524:
+		e.Kind = ErrUpstreamTimeout
+	}
+	return e
+}
+
+// parseError 页面解析失败
+func parseError(url string, err error) error {
+	return &UpstreamError{Kind: ErrParse, URL: url, Err: err}
+}
+
+// retryAfter 解析 Retry-After 头 (秒数或 HTTP 日期)
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
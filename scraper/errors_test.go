@@ -0,0 +1,65 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestUpstreamError(t *testing.T) {
+	status := map[string]int{
+		"ABP-404":   http.StatusNotFound,
+		"ABP-403":   http.StatusForbidden,
+		"ABP-429":   http.StatusTooManyRequests,
+		"ABP-504":   http.StatusGatewayTimeout,
+		"ABP-500":   http.StatusInternalServerError,
+		"ABP-EMPTY": http.StatusOK,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) == "ABP-429" {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(status[path.Base(r.URL.Path)])
+		_, _ = io.WriteString(w, "<html><body></body></html>")
+	}))
+	defer server.Close()
+
+	// 只有一个镜像，403 / 429 等不会切换到其他镜像
+	s := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors(server.URL)}
+	s.Client.SetRetryCount(0)
+	cases := []struct {
+		id   string
+		kind error
+	}{
+		{"ABP-404", ErrNotFound},
+		{"ABP-403", ErrBlocked},
+		{"ABP-429", ErrRateLimited},
+		{"ABP-504", ErrUpstreamTimeout},
+		{"ABP-500", ErrUpstream},
+		{"ABP-EMPTY", ErrParse},
+	}
+	for _, tc := range cases {
+		_, err := s.fetchMovieDetail(context.Background(), tc.id)
+		if !errors.Is(err, tc.kind) {
+			t.Errorf("fetchMovieDetail(%s) err = %v, want %v", tc.id, err, tc.kind)
+		}
+	}
+
+	_, err := s.fetchStarInfo(context.Background(), "ABP-429", "")
+	var upstream *UpstreamError
+	if !errors.As(err, &upstream) || upstream.RetryAfter != 30*time.Second || upstream.StatusCode != 429 {
+		t.Errorf("fetchStarInfo() err = %#v", err)
+	}
+
+	// 客户端取消不属于上游错误
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.fetchMovieDetail(ctx, "ABP-404"); !errors.Is(err, context.Canceled) || errors.Is(err, ErrUpstream) {
+		t.Errorf("canceled err = %v", err)
+	}
+}
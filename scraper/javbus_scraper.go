@@ -3,14 +3,17 @@ package scraper
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/jpeg"
 	_ "image/png"
+	"net/url"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	//_ "golang.org/x/image/webp"
@@ -20,8 +23,8 @@ import (
 	"github.com/fireinrain/javbus-api/cachedb"
 	"github.com/fireinrain/javbus-api/config"
 	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/metrics"
 	"github.com/fireinrain/javbus-api/model"
-	"github.com/fireinrain/javbus-api/utils"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -60,44 +63,92 @@ var (
 	memCache = cachedb.NewCache(3*time.Hour, 1*time.Hour)
 )
 
+func init() {
+	metrics.RegisterGaugeFunc("cache_entries", "Number of entries currently held in the in-memory scrape cache.", func() float64 {
+		return float64(memCache.Len())
+	})
+	metrics.RegisterGaugeFunc("cache_bytes", "Approximate size in bytes of the in-memory scrape cache.", func() float64 {
+		return float64(memCache.Stats().Bytes)
+	})
+}
+
 type JavbusScraper struct {
 	SiteUrl string
 	Client  *resty.Client
+	// 镜像域名，请求失败时自动切换
+	Mirrors *Mirrors
+	// 代理池，没有配置代理时为 nil
+	Proxies *ProxyPool
+	// 上游请求限流
+	Limiter *RateLimiter
+
+	// 最近一次 GetAccessJavbus 的结果，供健康检查使用
+	accessMu        sync.RWMutex
+	lastAccess      *model.JavbusAccessStatus
+	lastAccessCheck time.Time
 }
 
 func NewJavbusScraper(cfg *config.Config) *JavbusScraper {
-	client := NewRestyClient(cfg)
+	pool := newProxyPoolOrNil(cfg)
+	if pool != nil {
+		pool.StartHealthCheck(time.Duration(cfg.Proxy.HealthCheckInterval) * time.Second)
+	}
+	mirrors := NewMirrors(append([]string{cfg.Javbus.BaseURL}, cfg.Javbus.Mirrors...)...)
+	limiter := NewRateLimiter(cfg.RateLimit)
 	return &JavbusScraper{
-		SiteUrl: consts.JavBusURL,
-		Client:  client,
+		SiteUrl: mirrors.Primary(),
+		Client:  NewRestyClientWithLimiter(pool, limiter),
+		Mirrors: mirrors,
+		Proxies: pool,
+		Limiter: limiter,
 	}
 }
 
+// Name 实现 Provider 接口
+func (s *JavbusScraper) Name() string {
+	return JavbusProviderName
+}
+
 // -------------------------------------------------------------
 // 解析器核心逻辑
 // -------------------------------------------------------------
 
-// requestText 辅助方法：发送请求并返回 GoQuery Document
-func (s *JavbusScraper) requestDocument(url string, headers map[string]string) (*goquery.Document, error) {
-	// 1. 使用 Resty 链式调用
-	// .R() 创建请求对象
+// BaseURL 主域名，对外返回的链接统一使用该域名
+func (s *JavbusScraper) BaseURL() string {
+	return s.Mirrors.Primary()
+}
+
+// MovieURL 影片详情页地址，与返回的其他链接一样使用 BASE_URL
+func (s *JavbusScraper) MovieURL(id string) string {
+	return s.BaseURL() + "/" + id
+}
+
+// requestDocument 辅助方法：通过镜像请求站内路径 (以 / 开头) 并返回 GoQuery Document 和实际请求的地址
+func (s *JavbusScraper) requestDocument(ctx context.Context, path string, headers map[string]string) (*goquery.Document, string, error) {
+	// 1. 使用 Resty 链式调用，镜像被封时自动切换
 	// .SetHeaders() 直接支持 map[string]string，无需循环遍历
-	// .Get() 发起 GET 请求
-	resp, err := s.Client.R().
-		SetHeaders(headers).
-		Get(url)
+	resp, base, err := s.get(ctx, path, func(req *resty.Request, base string) {
+		req.SetHeaders(headers)
+	})
 
-	if err != nil {
-		return nil, err
+	url := base + path
+	if resp == nil {
+		metrics.DocumentRequestsTotal.WithLabelValues(metrics.StatusLabel(0, err)).Inc()
+		return nil, "", upstreamError(nil, url, err)
 	}
+	metrics.DocumentRequestsTotal.WithLabelValues(strconv.Itoa(resp.StatusCode())).Inc()
 
-	// 2. 检查状态码
-	if resp.StatusCode() != 200 {
-		return nil, fmt.Errorf("request failed with status code: %d", resp.StatusCode())
+	// 2. 检查状态码，转为 ErrNotFound / ErrBlocked 等错误
+	if err := upstreamError(resp, url, err); err != nil {
+		return nil, "", err
 	}
 	// 3. 转换 Resty Body 为 goquery Document
 	// Resty 的 resp.Body() 返回 []byte，goquery 需要 io.Reader
-	return goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+	if err != nil {
+		return nil, "", parseError(url, err)
+	}
+	return doc, url, nil
 }
 
 func parseFilterInfo(doc *goquery.Document, filterType, filterValue string) *model.FilterInfo {
@@ -119,7 +170,19 @@ func parseFilterInfo(doc *goquery.Document, filterType, filterValue string) *mod
 
 // 对应 getMoviesByPage
 // GetMoviesByPage 获取电影列表 (分页)
+// 并发的相同请求只会请求一次上游
 func (s *JavbusScraper) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	return s.GetMoviesByPageContext(context.Background(), q)
+}
+
+// GetMoviesByPageContext 与 GetMoviesByPage 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetMoviesByPageContext(ctx context.Context, q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	return loadOrFetch(ctx, MoviesCacheKey(q), listTTL, func(ctx context.Context) (*model.MoviesPage, error) {
+		return s.fetchMoviesPage(ctx, q)
+	})
+}
+
+func (s *JavbusScraper) fetchMoviesPage(ctx context.Context, q *model.GetMoviesQuery) (*model.MoviesPage, error) {
 	// 1. 处理页码 (int -> string)
 	page := "1"
 	pageInt, _ := strconv.Atoi(q.Page)
@@ -129,9 +192,9 @@ func (s *JavbusScraper) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesP
 
 	// 2. 构造 URL
 	// 基础前缀
-	prefix := consts.JavBusURL
+	prefix := ""
 	if q.Type != "" && q.Type != model.MovieTypeNormal {
-		prefix = fmt.Sprintf("%s/%s", consts.JavBusURL, q.Type)
+		prefix = "/" + string(q.Type)
 	}
 
 	// 叠加 FilterType
@@ -146,8 +209,11 @@ func (s *JavbusScraper) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesP
 			// /genre/id
 			url = fmt.Sprintf("%s/%s", prefix, q.FilterValue)
 		} else {
-			// /
+			// / 或 /uncensored
 			url = prefix
+			if url == "" {
+				url = "/"
+			}
 		}
 	} else {
 		if q.FilterType != "" {
@@ -169,13 +235,13 @@ func (s *JavbusScraper) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesP
 	}
 
 	// 4. 请求文档
-	doc, err := s.requestDocument(url, headers)
+	doc, _, err := s.requestDocument(ctx, url, headers)
 	if err != nil {
 		return nil, err
 	}
 
 	// 5. 解析列表
-	moviesPage := parseMoviesPage(doc)
+	moviesPage := parseMoviesPage(doc, s.Mirrors)
 
 	// 6. 解析 Filter 信息 (如果存在筛选)
 	// Node.js逻辑: filterType && filterValue ? parseFilterInfo(...) : undefined
@@ -189,16 +255,25 @@ func (s *JavbusScraper) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesP
 // 对应 getMoviesByKeywordAndPage
 // GetMoviesByKeywordAndPage
 func (s *JavbusScraper) GetMoviesByKeywordAndPage(keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
+	return s.GetMoviesByKeywordAndPageContext(context.Background(), keyword, q)
+}
+
+// GetMoviesByKeywordAndPageContext 与 GetMoviesByKeywordAndPage 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetMoviesByKeywordAndPageContext(ctx context.Context, keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
+	return loadOrFetch(ctx, SearchCacheKey(keyword, q), listTTL, func(ctx context.Context) (*model.SearchMoviesPage, error) {
+		return s.fetchSearchPage(ctx, keyword, q)
+	})
+}
+
+func (s *JavbusScraper) fetchSearchPage(ctx context.Context, keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
 	// 1. 构造 URL
-	prefix := fmt.Sprintf("%s/search", consts.JavBusURL)
+	prefix := "/search"
 	if q.Type != "" && q.Type != model.MovieTypeNormal {
-		prefix = fmt.Sprintf("%s/%s/search", consts.JavBusURL, q.Type)
+		prefix = fmt.Sprintf("/%s/search", q.Type)
 	}
 
-	page := q.Page
-	if page == "" {
-		page = "1"
-	}
+	// 与 SearchCacheKey 一致，非法页码按第一页请求，避免其他页的结果写入第一页的缓存
+	page := normalizePage(q.Page)
 
 	// Go 的 url.PathEscape 对应 encodeURIComponent，但保留了 '/'，通常够用，如果需要严格一致可以使用 query escape
 	url := fmt.Sprintf("%s/%s/%s&type=1", prefix, strings.TrimSpace(keyword), page)
@@ -211,13 +286,13 @@ func (s *JavbusScraper) GetMoviesByKeywordAndPage(keyword string, q *model.GetMo
 		headers["Cookie"] = "existmag=all"
 	}
 
-	doc, err := s.requestDocument(url, headers)
+	doc, _, err := s.requestDocument(ctx, url, headers)
 	if err != nil {
 		// 搜索结果为空时 JavBus 可能会返回 404，这里需要在上层处理
 		return nil, err
 	}
 
-	moviesPage := parseMoviesPage(doc)
+	moviesPage := parseMoviesPage(doc, s.Mirrors)
 
 	return &model.SearchMoviesPage{
 		MoviesPage: *moviesPage,
@@ -226,7 +301,7 @@ func (s *JavbusScraper) GetMoviesByKeywordAndPage(keyword string, q *model.GetMo
 }
 
 // parseMoviesPage 通用页面解析逻辑
-func parseMoviesPage(doc *goquery.Document) *model.MoviesPage {
+func parseMoviesPage(doc *goquery.Document, mirrors *Mirrors) *model.MoviesPage {
 	var movies []model.Movie
 
 	doc.Find("#waterfall #waterfall .item").Each(func(i int, s *goquery.Selection) {
@@ -243,8 +318,8 @@ func parseMoviesPage(doc *goquery.Document) *model.MoviesPage {
 			tags = append(tags, btn.Text())
 		})
 
-		// 格式化图片 URL (可能需要加上 host)
-		img := utils.FormatImageURL(rawImg)
+		// 格式化图片 URL (统一为主域名)
+		img := mirrors.Canonical(rawImg)
 
 		if id != "" {
 			movies = append(movies, model.Movie{
@@ -257,7 +332,14 @@ func parseMoviesPage(doc *goquery.Document) *model.MoviesPage {
 		}
 	})
 
-	// 分页信息解析
+	return &model.MoviesPage{
+		Movies:     movies,
+		Pagination: parsePagination(doc),
+	}
+}
+
+// parsePagination 解析列表页底部的分页
+func parsePagination(doc *goquery.Document) model.Pagination {
 	activePageStr := doc.Find(".pagination .active a").Text()
 	if activePageStr == "" {
 		activePageStr = "1"
@@ -278,14 +360,11 @@ func parseMoviesPage(doc *goquery.Document) *model.MoviesPage {
 		nextPage = np
 	}
 
-	return &model.MoviesPage{
-		Movies: movies,
-		Pagination: model.Pagination{
-			CurrentPage: currentPage,
-			HasNextPage: hasNextPage,
-			NextPage:    nextPage,
-			Pages:       pages,
-		},
+	return model.Pagination{
+		CurrentPage: currentPage,
+		HasNextPage: hasNextPage,
+		NextPage:    nextPage,
+		Pages:       pages,
 	}
 }
 
@@ -328,7 +407,7 @@ func shallowCopyMap(original map[string]string) map[string]string {
 }
 
 // GetImageDimensions 获取图片尺寸而不下载全图
-func getImageDimensions(client *resty.Client, url string, pageUrl string) (int, int, string, error) {
+func getImageDimensions(ctx context.Context, client *resty.Client, url string, pageUrl string) (int, int, string, error) {
 	// 关键点 1: SetDoNotParseResponse(true)
 	// 告诉 Resty 不要自动读取和关闭 Body，把 Body 的控制权交给我们
 	// 这样我们就可以像操作文件流一样操作网络流
@@ -337,7 +416,7 @@ func getImageDimensions(client *resty.Client, url string, pageUrl string) (int,
 	headers["Range"] = "bytes=0-512"
 	headers["Cookie"] = ""
 	headers["Accept"] = "image/webp,image/apng,image/*,*/*;q=0.8"
-	resp, err := client.R().SetHeaders(headers).
+	resp, err := client.R().SetContext(ctx).SetHeaders(headers).
 		SetDoNotParseResponse(true).
 		// 可选优化: 加上 Range 头，只请求前 32KB 数据。
 		// 大多数图片头部都在前几 KB，但这取决于服务器是否支持 Range。
@@ -384,36 +463,62 @@ func getImageDimensions(client *resty.Client, url string, pageUrl string) (int,
 	return config.Width, config.Height, format, nil
 }
 
-func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
-	// 1. 先检查缓存
-	cacheKey := "movie:" + id
-	if cachedData, found := memCache.Get(cacheKey); found {
-		if detail, ok := cachedData.(*model.MovieDetail); ok {
-			return detail, nil
-		}
+// GetImage 下载图片 (封面、海报等)，referer 为图片所在页面
+func (s *JavbusScraper) GetImage(url string, referer string) ([]byte, error) {
+	return s.GetImageContext(context.Background(), url, referer)
+}
+
+// GetImageContext 与 GetImage 相同，ctx 取消或超时后停止下载
+func (s *JavbusScraper) GetImageContext(ctx context.Context, url string, referer string) ([]byte, error) {
+	headers := shallowCopyMap(ReqHeaders)
+	headers["Referer"] = referer
+	headers["Cookie"] = ""
+	headers["Accept"] = "image/webp,image/apng,image/*,*/*;q=0.8"
+
+	resp, err := s.Client.R().SetContext(ctx).SetHeaders(headers).Get(url)
+	if err := upstreamError(resp, url, err); err != nil {
+		return nil, err
 	}
+	return resp.Body(), nil
+}
+
+// GetMovieDetail 先检查缓存，未命中时请求详情页，并发的相同请求共享一次上游请求
+func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
+	return s.GetMovieDetailContext(context.Background(), id)
+}
+
+// GetMovieDetailContext 与 GetMovieDetail 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetMovieDetailContext(ctx context.Context, id string) (*model.MovieDetail, error) {
+	return loadOrFetch(ctx, MovieCacheKey(id), detailTTL, func(ctx context.Context) (*model.MovieDetail, error) {
+		return s.fetchMovieDetail(ctx, id)
+	})
+}
 
-	// 2. 缓存未命中，发起请求
-	url := fmt.Sprintf("%s/%s", consts.JavBusURL, id)
+func (s *JavbusScraper) fetchMovieDetail(ctx context.Context, id string) (*model.MovieDetail, error) {
+	// 发起请求
 	var cookieStr = ""
 	headerMap := shallowCopyMap(ReqHeaders)
 	headerMap["Cookie"] = cookieStr
 	// 这里需要原始 HTML 字符串来做正则匹配 (gid/uc)，所以不能只用 goquery
 	// 为了复用 requestDocument 的逻辑，我们可以稍作修改，或者这里单独发请求
 	// 为了简单，我们先获取 Document，再获取 HTML 字符串
-	doc, err := s.requestDocument(url, headerMap)
+	doc, url, err := s.requestDocument(ctx, "/"+id, headerMap)
 	if err != nil {
 		return nil, err
 	}
 
 	html, _ := doc.Html()
+	mirrors := s.Mirrors
 
 	// 1. 标题与图片
 	title := doc.Find(".container h3").Text()
+	if strings.TrimSpace(title) == "" {
+		return nil, parseError(url, errors.New("movie title not found"))
+	}
 	bigImg := doc.Find(".container .movie .bigImage img").AttrOr("src", "")
-	imgURL := utils.FormatImageURL(bigImg)
+	imgURL := s.Mirrors.Canonical(bigImg)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	probeCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 	// 2. 图片尺寸探测 (Probe)
 	var imageSize *model.ImageSize
@@ -429,7 +534,7 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 		}, 1)
 		go func() {
 			defer close(imgChan)
-			width, height, _, err := getImageDimensions(s.Client, imgURL, url)
+			width, height, _, err := getImageDimensions(probeCtx, s.Client, imgURL, s.Mirrors.Canonical(url))
 			imgChan <- struct {
 				width, height int
 				format        string
@@ -444,9 +549,13 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 					Width:  result.width,
 					Height: result.height,
 				}
+				metrics.ImageProbeTotal.WithLabelValues("ok").Inc()
+			} else {
+				metrics.ImageProbeTotal.WithLabelValues("error").Inc()
 			}
-		case <-ctx.Done():
+		case <-probeCtx.Done():
 			// 超时则跳过图片尺寸处理
+			metrics.ImageProbeTotal.WithLabelValues("timeout").Inc()
 		}
 	}
 
@@ -481,17 +590,8 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 					href := a.AttrOr("href", "")
 					name := strings.TrimSpace(a.Text())
 
-					// ID 提取逻辑
-					isUncensored := strings.Contains(href, "uncensored")
-					computedPrefix := prefix
-					if isUncensored {
-						computedPrefix = "uncensored/" + prefix
-					}
-
-					id := strings.Replace(href, consts.JavBusURL+"/"+computedPrefix+"/", "", 1)
-					if id != "" && isUncensored {
-						id = "uncensored/" + id
-					}
+					// ID 提取逻辑，只看路径部分，与镜像域名无关
+					id := linkID(mirrors.Path(href), prefix)
 
 					prop = &model.Property{ID: id, Name: name}
 				}
@@ -526,7 +626,7 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 				if a != nil && a.Length() > 0 {
 					name := a.Text()
 					href := a.AttrOr("href", "")
-					id := strings.Split(href, "/genre/")[1] // 简化提取
+					id := linkID(mirrors.Path(href), "genre")
 					genres = append(genres, model.Property{ID: id, Name: name})
 				}
 			})
@@ -538,7 +638,7 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 				if a != nil && a.Length() > 0 {
 					name := a.Text()
 					href := a.AttrOr("href", "")
-					id := strings.Split(href, "/star/")[1] // 简化提取
+					id := linkID(mirrors.Path(href), "star")
 					stars = append(stars, model.Property{ID: id, Name: name})
 				}
 			})
@@ -575,20 +675,20 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 		samples = append(samples, model.Sample{
 			Alt:       alt,
 			ID:        id,
-			Thumbnail: utils.FormatImageURL(thumb),
-			Src:       utils.FormatImageURL(href),
+			Thumbnail: mirrors.Canonical(thumb),
+			Src:       mirrors.Canonical(href),
 		})
 	})
 
 	// 6. 相似影片
 	var similar []model.SimilarMovie
-	doc.Find("#related-waterfall a").Each(func(i int, s *goquery.Selection) {
-		href := s.AttrOr("href", "")
-		parts := strings.Split(href, "/")
+	doc.Find("#related-waterfall a").Each(func(i int, sel *goquery.Selection) {
+		href := sel.AttrOr("href", "")
+		parts := strings.Split(strings.TrimSuffix(href, "/"), "/")
 		id := parts[len(parts)-1]
-		title := s.AttrOr("title", "")
-		img := s.Find("img").AttrOr("src", "")
-		fImg := utils.FormatImageURL(img)
+		title := sel.AttrOr("title", "")
+		img := sel.Find("img").AttrOr("src", "")
+		fImg := mirrors.Canonical(img)
 
 		similar = append(similar, model.SimilarMovie{
 			ID:    id,
@@ -615,49 +715,139 @@ func (s *JavbusScraper) GetMovieDetail(id string) (*model.MovieDetail, error) {
 		GID:           gidStr,
 		UC:            ucStr,
 	}
-	// 3. 缓存结果
-	memCache.Set(cacheKey, movieDetail, consts.CacheExpire)
 	return movieDetail, nil
 }
 
+// linkID 从站内路径中提取筛选 ID，例如 studio/7q 得到 7q，uncensored/studio/3n 得到 uncensored/3n
+func linkID(path string, prefix string) string {
+	uncensored := strings.HasPrefix(path, "uncensored/")
+	path = strings.TrimPrefix(path, "uncensored/")
+	if !strings.HasPrefix(path, prefix+"/") {
+		return ""
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(path, prefix+"/"), "/")
+	if id != "" && uncensored {
+		id = "uncensored/" + id
+	}
+	return id
+}
+
 // 对应 getStarInfo
 // GetStarInfo 获取演员详细信息
 // 对应 TS: export async function getStarInfo(starId: string, type?: MovieType)
 func (s *JavbusScraper) GetStarInfo(starId string, movieType string) (*model.StarInfo, error) {
-	// 1. 构造 URL 前缀
-	prefix := consts.JavBusURL
-	// 对应 !type || type === 'normal'
-	if movieType != "" && movieType != "normal" {
-		prefix = fmt.Sprintf("%s/%s", consts.JavBusURL, movieType)
+	return s.GetStarInfoContext(context.Background(), starId, movieType)
+}
+
+// GetStarInfoContext 与 GetStarInfo 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetStarInfoContext(ctx context.Context, starId string, movieType string) (*model.StarInfo, error) {
+	return loadOrFetch(ctx, StarCacheKey(starId, movieType), detailTTL, func(ctx context.Context) (*model.StarInfo, error) {
+		return s.fetchStarInfo(ctx, starId, movieType)
+	})
+}
+
+func (s *JavbusScraper) fetchStarInfo(ctx context.Context, starId string, movieType string) (*model.StarInfo, error) {
+	info, _, err := s.fetchStarPage(ctx, starId, &model.StarMoviesQuery{Type: model.MovieType(movieType)})
+	return info, err
+}
+
+// fetchStarPage 请求演员页面，同时解析演员信息和该页的作品
+func (s *JavbusScraper) fetchStarPage(ctx context.Context, starId string, q *model.StarMoviesQuery) (*model.StarInfo, *model.MoviesPage, error) {
+	// 1. 构造路径: /star/id 或 /uncensored/star/id/2
+	path := fmt.Sprintf("%s/star/%s", typePrefix(q.Type), starId)
+	if page := normalizePage(q.Page); page != "1" {
+		path += "/" + page
 	}
-	url := fmt.Sprintf("%s/star/%s", prefix, starId)
 
-	// 2. 发起请求 (Resty)
-	resp, err := s.Client.R().Get(url)
-	if err != nil {
-		return nil, err
+	// 2. 与 fetchMoviesPage 一致，未指定 magnet 时按 all 处理 (缓存 key 也是如此)
+	headers := map[string]string{}
+	if q.Magnet == model.MagnetTypeExist {
+		headers["Cookie"] = "existmag=mag"
+	} else {
+		headers["Cookie"] = "existmag=all"
 	}
 
-	// 3. 加载 HTML
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(resp.Body()))
+	// 3. 演员不存在时返回 ErrNotFound
+	doc, url, err := s.requestDocument(ctx, path, headers)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	// 4. 解析
+	info := parseStarInfo(doc, starId, s.Mirrors)
+	if info.Name == "" {
+		return nil, nil, parseError(url, errors.New("star name not found"))
+	}
+	return info, parseMoviesPage(doc, s.Mirrors), nil
+}
+
+// GetStarMovies 获取演员信息和某一页作品，q.All 为 true 时返回全部作品
+func (s *JavbusScraper) GetStarMovies(starId string, q *model.StarMoviesQuery) (*model.StarMoviesPage, error) {
+	return s.GetStarMoviesContext(context.Background(), starId, q)
+}
+
+// GetStarMoviesContext 与 GetStarMovies 相同，ctx 取消或超时后停止请求上游
+// 演员信息和作品来自同一个页面，顺便写入演员信息的缓存
+func (s *JavbusScraper) GetStarMoviesContext(ctx context.Context, starId string, q *model.StarMoviesQuery) (*model.StarMoviesPage, error) {
+	if q.All {
+		return s.getStarFilmography(ctx, starId, q)
 	}
+	return loadOrFetch(ctx, StarMoviesCacheKey(starId, q), listTTL, func(ctx context.Context) (*model.StarMoviesPage, error) {
+		info, page, err := s.fetchStarPage(ctx, starId, q)
+		if err != nil {
+			return nil, err
+		}
+		if normalizePage(q.Page) == "1" {
+			saveCache(StarCacheKey(starId, string(q.Type)), info, detailTTL)
+		}
+		return &model.StarMoviesPage{Star: info, MoviesPage: *page}, nil
+	})
+}
 
-	// 4. 解析并返回
-	return parseStarInfo(doc, starId), nil
+// getStarFilmography 从第 1 页开始逐页抓取演员的全部作品 (每页单独缓存)
+// 最多抓取 StarMoviesMaxPages 页，没抓完时 Pagination.HasNextPage 为 true，NextPage 为下一页
+func (s *JavbusScraper) getStarFilmography(ctx context.Context, starId string, q *model.StarMoviesQuery) (*model.StarMoviesPage, error) {
+	result := &model.StarMoviesPage{
+		MoviesPage: model.MoviesPage{
+			Movies:     []model.Movie{},
+			Pagination: model.Pagination{CurrentPage: 1, Pages: []int{}},
+		},
+	}
+	for n := 1; n <= consts.StarMoviesMaxPages; n++ {
+		pageQuery := *q
+		pageQuery.All = false
+		pageQuery.Page = strconv.Itoa(n)
+		page, err := s.GetStarMoviesContext(ctx, starId, &pageQuery)
+		if err != nil {
+			return nil, err
+		}
+		if result.Star == nil {
+			result.Star = page.Star
+		}
+		result.Movies = append(result.Movies, page.Movies...)
+		result.Pagination.Pages = append(result.Pagination.Pages, n)
+
+		if !page.Pagination.HasNextPage {
+			result.Pagination.HasNextPage = false
+			result.Pagination.NextPage = 0
+			return result, nil
+		}
+		result.Pagination.HasNextPage = true
+		result.Pagination.NextPage = n + 1
+	}
+	return result, nil
 }
 
 // parseStarInfo 解析演员详情 HTML
 // 对应 TS: export function parseStarInfo(pageHTML: string, starId: string): StarInfo
-func parseStarInfo(doc *goquery.Document, starId string) *model.StarInfo {
+func parseStarInfo(doc *goquery.Document, starId string, mirrors *Mirrors) *model.StarInfo {
 	// 1. 定位容器: #waterfall .item .avatar-box
 	box := doc.Find("#waterfall .item .avatar-box")
 
 	// 2. 解析头像 (Avatar)
 	// 对应 TS: formatImageUrl(doc?.querySelector('.photo-frame img')?.getAttribute('src')) ?? null
 	rawAvatar := box.Find(".photo-frame img").AttrOr("src", "")
-	formattedAvatar := utils.FormatImageURL(rawAvatar)
+	formattedAvatar := mirrors.Canonical(rawAvatar)
 
 	var avatarPtr string
 	if formattedAvatar != "" {
@@ -724,27 +914,160 @@ func parseStarInfo(doc *goquery.Document, starId string) *model.StarInfo {
 	}
 }
 
+// GetStars 获取演员列表 (有码 /actresses，无码 /uncensored/actresses)
+func (s *JavbusScraper) GetStars(q *model.GetStarsQuery) (*model.StarsPage, error) {
+	return s.GetStarsContext(context.Background(), q)
+}
+
+// GetStarsContext 与 GetStars 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetStarsContext(ctx context.Context, q *model.GetStarsQuery) (*model.StarsPage, error) {
+	return loadOrFetch(ctx, StarsCacheKey(q), listTTL, func(ctx context.Context) (*model.StarsPage, error) {
+		path := typePrefix(q.Type) + "/actresses"
+		if page := normalizePage(q.Page); page != "1" {
+			path += "/" + page
+		}
+		doc, _, err := s.requestDocument(ctx, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		return parseStarsPage(doc, s.Mirrors), nil
+	})
+}
+
+// SearchStars 按名字搜索演员
+func (s *JavbusScraper) SearchStars(keyword string, q *model.GetStarsQuery) (*model.SearchStarsPage, error) {
+	return s.SearchStarsContext(context.Background(), keyword, q)
+}
+
+// SearchStarsContext 与 SearchStars 相同，ctx 取消或超时后停止请求上游
+// 没有搜索结果时 JavBus 返回 404，对应 ErrNotFound
+func (s *JavbusScraper) SearchStarsContext(ctx context.Context, keyword string, q *model.GetStarsQuery) (*model.SearchStarsPage, error) {
+	keyword = strings.TrimSpace(keyword)
+	return loadOrFetch(ctx, StarSearchCacheKey(keyword, q), listTTL, func(ctx context.Context) (*model.SearchStarsPage, error) {
+		// 关键字中的 / ? # % 需要转义，否则请求的是其他页面
+		path := fmt.Sprintf("%s/searchstar/%s/%s", typePrefix(q.Type), url.PathEscape(keyword), normalizePage(q.Page))
+		doc, _, err := s.requestDocument(ctx, path, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &model.SearchStarsPage{StarsPage: *parseStarsPage(doc, s.Mirrors), Keyword: keyword}, nil
+	})
+}
+
+// typePrefix 无码为 /uncensored，有码为空
+func typePrefix(movieType model.MovieType) string {
+	if t := normalizeType(movieType); t != string(model.MovieTypeNormal) {
+		return "/" + t
+	}
+	return ""
+}
+
+// parseStarsPage 解析演员列表、演员搜索页面，分页与影片列表相同
+// 无码演员的链接为 uncensored/star/xx，返回的 ID 统一去掉 uncensored/ 前缀 (与 GetStarInfo 的参数一致)
+func parseStarsPage(doc *goquery.Document, mirrors *Mirrors) *model.StarsPage {
+	stars := []model.Star{}
+	doc.Find("#waterfall .item a.avatar-box").Each(func(i int, a *goquery.Selection) {
+		id := strings.TrimPrefix(linkID(mirrors.Path(a.AttrOr("href", "")), "star"), "uncensored/")
+		if id == "" {
+			return
+		}
+		img := a.Find(".photo-frame img")
+		name := strings.TrimSpace(a.Find(".photo-info span").First().Text())
+		if name == "" {
+			name = strings.TrimSpace(img.AttrOr("title", ""))
+		}
+		stars = append(stars, model.Star{
+			Avatar: mirrors.Canonical(img.AttrOr("src", "")),
+			ID:     id,
+			Name:   name,
+		})
+	})
+
+	return &model.StarsPage{
+		Stars:      stars,
+		Pagination: parsePagination(doc),
+	}
+}
+
+// GetGenres 获取全部类别，按分组返回
+func (s *JavbusScraper) GetGenres(movieType model.MovieType) (*model.GenreCatalog, error) {
+	return s.GetGenresContext(context.Background(), movieType)
+}
+
+// GetGenresContext 与 GetGenres 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetGenresContext(ctx context.Context, movieType model.MovieType) (*model.GenreCatalog, error) {
+	return loadOrFetch(ctx, GenresCacheKey(movieType), catalogTTL, func(ctx context.Context) (*model.GenreCatalog, error) {
+		return s.fetchGenres(ctx, movieType)
+	})
+}
+
+func (s *JavbusScraper) fetchGenres(ctx context.Context, movieType model.MovieType) (*model.GenreCatalog, error) {
+	// 有码 /genre，无码 /uncensored/genre
+	movieType = model.MovieType(normalizeType(movieType))
+	path := typePrefix(movieType) + "/genre"
+
+	doc, url, err := s.requestDocument(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	categories := parseGenres(doc, s.Mirrors)
+	if len(categories) == 0 {
+		return nil, parseError(url, errors.New("genre list not found"))
+	}
+	return &model.GenreCatalog{Type: movieType, Categories: categories}, nil
+}
+
+// parseGenres 解析类别页面，每个 .genre-box 前面的 h4 为分组名称
+// 无码类别的链接为 uncensored/genre/xx，返回的 ID 统一去掉 uncensored/ 前缀
+func parseGenres(doc *goquery.Document, mirrors *Mirrors) []model.GenreCategory {
+	var categories []model.GenreCategory
+	doc.Find(".genre-box").Each(func(i int, box *goquery.Selection) {
+		category := model.GenreCategory{
+			Name:   strings.TrimSpace(box.PrevAllFiltered("h4").First().Text()),
+			Genres: []model.Property{},
+		}
+		box.Find("a").Each(func(j int, a *goquery.Selection) {
+			id := strings.TrimPrefix(linkID(mirrors.Path(a.AttrOr("href", "")), "genre"), "uncensored/")
+			name := strings.TrimSpace(a.Text())
+			if id == "" || name == "" {
+				return
+			}
+			category.Genres = append(category.Genres, model.Property{ID: id, Name: name})
+		})
+		if len(category.Genres) > 0 {
+			categories = append(categories, category)
+		}
+	})
+	return categories
+}
+
 // 对应 getMovieMagnets
 // GetMovieMagnets 获取磁力链接 (Ajax)
 func (s *JavbusScraper) GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error) {
-	cacheKey := "mag:" + movieId + ":" + gid + ":" + uc + ":" + sortBy + ":" + sortOrder
-	if cachedData, found := memCache.Get(cacheKey); found {
-		if detail, ok := cachedData.([]model.Magnet); ok {
-			return detail, nil
-		}
-	}
+	return s.GetMovieMagnetsContext(context.Background(), movieId, gid, uc, sortBy, sortOrder)
+}
+
+// GetMovieMagnetsContext 与 GetMovieMagnets 相同，ctx 取消或超时后停止请求上游
+func (s *JavbusScraper) GetMovieMagnetsContext(ctx context.Context, movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error) {
+	return loadOrFetch(ctx, MagnetCacheKey(movieId, gid, uc, sortBy, sortOrder), magnetTTL, func(ctx context.Context) ([]model.Magnet, error) {
+		return s.fetchMovieMagnets(ctx, movieId, gid, uc, sortBy, sortOrder)
+	})
+}
+
+func (s *JavbusScraper) fetchMovieMagnets(ctx context.Context, movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error) {
 	// 1. 使用 Resty 发起请求
 	// Resty 会自动处理 URL 参数编码，不需要手动 fmt.Sprintf 拼接参数
-	resp, err := s.Client.R().
-		SetQueryParams(map[string]string{
+	// Referer 必须与请求的镜像一致
+	const path = "/ajax/uncledatoolsbyajax.php"
+	resp, base, err := s.get(ctx, path, func(req *resty.Request, base string) {
+		req.SetQueryParams(map[string]string{
 			"gid":  gid,
 			"lang": "zh",
 			"uc":   uc,
-		}).
-		SetHeader("Referer", fmt.Sprintf("%s/%s", consts.JavBusURL, movieId)).
-		Get(consts.JavBusURL + "/ajax/uncledatoolsbyajax.php")
+		}).SetHeader("Referer", fmt.Sprintf("%s/%s", base, movieId))
+	})
 
-	if err != nil {
+	if err := upstreamError(resp, base+path, err); err != nil {
 		return nil, err
 	}
 	validHtml := fmt.Sprintf("<table>%s</table>", resp.String())
@@ -752,7 +1075,7 @@ func (s *JavbusScraper) GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder stri
 	// Resty 的 Body() 返回 []byte，需要转换为 Reader 给 goquery 使用
 	doc, err := goquery.NewDocumentFromReader(bytes.NewReader([]byte(validHtml)))
 	if err != nil {
-		return nil, err
+		return nil, parseError(base+path, err)
 	}
 
 	var magnets []model.Magnet
@@ -867,18 +1190,46 @@ func (s *JavbusScraper) GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder stri
 		}
 		return valA > valB
 	})
-	//set cache
-	memCache.Set(cacheKey, magnets, consts.CacheExpire)
 	return magnets, nil
 }
 
-// GetAccessJavbus 查询是否可以访问javbus
+// CacheEntries 返回内存缓存中的条目数
+func CacheEntries() int {
+	return memCache.Len()
+}
+
+// LastAccessStatus 返回最近一次访问检测的结果和检测时间，从未检测过时返回 nil
+func (s *JavbusScraper) LastAccessStatus() (*model.JavbusAccessStatus, time.Time) {
+	s.accessMu.RLock()
+	defer s.accessMu.RUnlock()
+	return s.lastAccess, s.lastAccessCheck
+}
+
+// GetAccessJavbus 查询是否可以访问javbus，并记录检测结果
 func (s *JavbusScraper) GetAccessJavbus() (*model.JavbusAccessStatus, error) {
-	// 1. 使用 Resty 发起请求
-	// Resty 会自动处理 URL 参数编码，不需要手动 fmt.Sprintf 拼接参数
-	resp, err := s.Client.R().
-		SetHeader("User-Agent", consts.UserAgent).
-		Get(consts.JavBusURL)
+	return s.GetAccessJavbusContext(context.Background())
+}
+
+// GetAccessJavbusContext 与 GetAccessJavbus 相同，ctx 取消时返回的结果不会被记录
+func (s *JavbusScraper) GetAccessJavbusContext(ctx context.Context) (*model.JavbusAccessStatus, error) {
+	status, err := s.checkAccessJavbus(ctx)
+	if ctx.Err() != nil {
+		return status, err
+	}
+
+	s.accessMu.Lock()
+	s.lastAccess = status
+	s.lastAccessCheck = time.Now()
+	s.accessMu.Unlock()
+
+	return status, err
+}
+
+func (s *JavbusScraper) checkAccessJavbus(ctx context.Context) (*model.JavbusAccessStatus, error) {
+	// 1. 依次访问各个镜像首页，任意一个可以访问即可
+	resp, base, err := s.get(ctx, "/", func(req *resty.Request, base string) {
+		req.SetHeader("User-Agent", consts.UserAgent)
+	})
 
 	if err != nil {
 		return &model.JavbusAccessStatus{
@@ -890,12 +1241,12 @@ func (s *JavbusScraper) GetAccessJavbus() (*model.JavbusAccessStatus, error) {
 	if resp != nil && resp.StatusCode() == 200 {
 		return &model.JavbusAccessStatus{
 			Access:  true,
-			Message: "access javbus.com success!",
+			Message: fmt.Sprintf("access %s success!", base),
 		}, nil
 	}
 
 	return &model.JavbusAccessStatus{
 		Access:  false,
-		Message: fmt.Sprintf("you may need use proxy for access javbus.com: %v", err),
+		Message: fmt.Sprintf("you may need use proxy for access %s: status code %d", base, resp.StatusCode()),
 	}, nil
 }
@@ -2,9 +2,15 @@ package scraper
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/PuerkitoBio/goquery"
@@ -86,3 +92,229 @@ func TestJavbusScraper_GetMovieMagnets(t *testing.T) {
 	})
 	fmt.Println(magnets)
 }
+
+const genresHTML = `<html><body><div class="container-fluid">
+<h4>主題</h4>
+<div class="row genre-box">
+<a class="col-lg-1 text-center" href="%[1]s/%[2]sgenre/1o">中出</a>
+<a class="col-lg-1 text-center" href="/%[2]sgenre/2h"> 巨乳 </a>
+<a class="col-lg-1 text-center" href="https://example.com/genre/zz">站外</a>
+</div>
+<h4>角色</h4>
+<div class="row genre-box">
+<a class="col-lg-1 text-center" href="%[1]s/%[2]sgenre/e">女教師</a>
+</div>
+<h4>空分組</h4>
+<div class="row genre-box"></div>
+</div></body></html>`
+
+func TestFetchGenres(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/genre":
+			fmt.Fprintf(w, genresHTML, server.URL, "")
+		case "/uncensored/genre":
+			fmt.Fprintf(w, genresHTML, server.URL, "uncensored/")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	s := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors(server.URL)}
+
+	want := []model.GenreCategory{
+		{Name: "主題", Genres: []model.Property{{ID: "1o", Name: "中出"}, {ID: "2h", Name: "巨乳"}}},
+		{Name: "角色", Genres: []model.Property{{ID: "e", Name: "女教師"}}},
+	}
+	for _, movieType := range []model.MovieType{"", model.MovieTypeUncensored} {
+		catalog, err := s.fetchGenres(context.Background(), movieType)
+		if err != nil {
+			t.Fatalf("type %q: %v", movieType, err)
+		}
+		if catalog.Type != model.MovieType(normalizeType(movieType)) {
+			t.Errorf("type %q: catalog type = %q", movieType, catalog.Type)
+		}
+		if !reflect.DeepEqual(catalog.Categories, want) {
+			t.Errorf("type %q: categories = %+v, want %+v", movieType, catalog.Categories, want)
+		}
+	}
+
+	if GenresCacheKey("") != GenresCacheKey(model.MovieTypeNormal) {
+		t.Errorf("empty type and normal should share a cache key")
+	}
+}
+
+const starsHTML = `<html><body><div id="waterfall"><div id="waterfall">
+<div class="item"><a class="avatar-box text-center" href="%[1]s/%[2]sstar/okq">
+<div class="photo-frame"><img src="/pics/actress/okq_a.jpg" title="三上悠亜"></div>
+<div class="photo-info"><span>三上悠亜</span></div></a></div>
+<div class="item"><a class="avatar-box text-center" href="/%[2]sstar/2xi">
+<div class="photo-frame"><img src="%[1]s/pics/actress/2xi_a.jpg" title="葵つかさ"></div>
+<div class="photo-info"></div></a></div>
+</div></div>
+<ul class="pagination pagination-lg">
+<li><a href="/%[2]sactresses/1">1</a></li>
+<li class="active"><a href="/%[2]sactresses/2">2</a></li>
+<li><a href="/%[2]sactresses/3">3</a></li>
+<li><a id="next" href="/%[2]sactresses/3">下一頁</a></li>
+</ul></body></html>`
+
+func TestStarsPages(t *testing.T) {
+	var server *httptest.Server
+	var paths, escaped []string
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		escaped = append(escaped, r.URL.EscapedPath())
+		switch r.URL.Path {
+		case "/actresses/2":
+			fmt.Fprintf(w, starsHTML, server.URL, "")
+		case "/uncensored/searchstar/葵/1":
+			fmt.Fprintf(w, starsHTML, server.URL, "uncensored/")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	s := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors(server.URL)}
+	t.Cleanup(func() {
+		memCache.DeletePrefix("stars:")
+		memCache.DeletePrefix("starsearch:")
+	})
+
+	want := []model.Star{
+		{Avatar: server.URL + "/pics/actress/okq_a.jpg", ID: "okq", Name: "三上悠亜"},
+		{Avatar: server.URL + "/pics/actress/2xi_a.jpg", ID: "2xi", Name: "葵つかさ"},
+	}
+	wantPagination := model.Pagination{CurrentPage: 2, HasNextPage: true, NextPage: 3, Pages: []int{1, 2, 3}}
+
+	page, err := s.GetStarsContext(context.Background(), &model.GetStarsQuery{Page: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(page.Stars, want) || !reflect.DeepEqual(page.Pagination, wantPagination) {
+		t.Errorf("stars page = %+v", page)
+	}
+
+	search, err := s.SearchStarsContext(context.Background(), " 葵 ", &model.GetStarsQuery{Type: model.MovieTypeUncensored})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if search.Keyword != "葵" || !reflect.DeepEqual(search.Stars, want) {
+		t.Errorf("search page = %+v", search)
+	}
+
+	// 没有结果时返回 ErrNotFound，由接口转为空列表
+	if _, err := s.SearchStarsContext(context.Background(), "nobody", &model.GetStarsQuery{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("empty search: err = %v, want ErrNotFound", err)
+	}
+	if paths[len(paths)-1] != "/searchstar/nobody/1" {
+		t.Errorf("paths = %v", paths)
+	}
+
+	// 关键字中的特殊字符转义后放在同一段路径中
+	_, _ = s.SearchStarsContext(context.Background(), "a/b?c", &model.GetStarsQuery{})
+	if got := escaped[len(escaped)-1]; got != "/searchstar/a%2Fb%3Fc/1" {
+		t.Errorf("escaped path = %s, want /searchstar/a%%2Fb%%3Fc/1", got)
+	}
+
+	if StarsCacheKey(&model.GetStarsQuery{}) != StarsCacheKey(&model.GetStarsQuery{Type: model.MovieTypeNormal, Page: "1"}) {
+		t.Errorf("default query and page 1 should share a cache key")
+	}
+}
+
+// starPageHTML 演员页面，第一项为演员信息，其余为作品
+const starPageHTML = `<html><body><div id="waterfall"><div id="waterfall">
+<div class="item"><div class="avatar-box"><div class="photo-frame"><img src="/pics/actress/tst_a.jpg" title="テスト"></div>
+<div class="photo-info"><span class="pb10">テスト</span><p>生日: 1990-08-14</p></div></div></div>
+<div class="item"><a class="movie-box" href="/TST-%[1]d1"><div class="photo-frame"><img src="/thumb/%[1]d1.jpg" title="作品 %[1]d1"></div>
+<div class="photo-info"><span><date>TST-%[1]d1</date> / <date>2024-01-0%[1]d</date></span></div></a></div>
+<div class="item"><a class="movie-box" href="/TST-%[1]d2"><div class="photo-frame"><img src="/thumb/%[1]d2.jpg" title="作品 %[1]d2"></div>
+<div class="photo-info"><span><date>TST-%[1]d2</date> / <date>2024-01-0%[1]d</date></span></div></a></div>
+</div></div>
+<ul class="pagination pagination-lg">
+<li><a href="/star/tst">1</a></li><li><a href="/star/tst/2">2</a></li><li><a href="/star/tst/3">3</a></li>
+<li class="active"><a href="/star/tst/%[1]d">%[1]d</a></li>%[2]s
+</ul></body></html>`
+
+func TestStarMovies(t *testing.T) {
+	var cookies sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages := map[string]int{"/star/tst": 1, "/star/tst/2": 2, "/star/tst/3": 3}
+		n, ok := pages[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		cookies.Store(r.URL.Path, r.Header.Get("Cookie"))
+		next := ""
+		if n < 3 {
+			next = `<li><a id="next" href="#">下一頁</a></li>`
+		}
+		fmt.Fprintf(w, starPageHTML, n, next)
+	}))
+	defer server.Close()
+	s := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors(server.URL)}
+	t.Cleanup(func() {
+		memCache.DeletePrefix("starmovies:")
+		memCache.Delete(StarCacheKey("tst", ""))
+	})
+
+	page, err := s.GetStarMoviesContext(context.Background(), "tst", &model.StarMoviesQuery{Page: "2", Magnet: model.MagnetTypeExist})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Star == nil || page.Star.Name != "テスト" || len(page.Movies) != 2 || page.Movies[0].ID != "TST-21" {
+		t.Fatalf("page 2 = %+v", page)
+	}
+	if page.Pagination.CurrentPage != 2 || !page.Pagination.HasNextPage {
+		t.Errorf("page 2 pagination = %+v", page.Pagination)
+	}
+	if cookie, _ := cookies.Load("/star/tst/2"); cookie != "existmag=mag" {
+		t.Errorf("cookie = %v, want existmag=mag", cookie)
+	}
+	if _, _, found := memCache.Lookup(StarCacheKey("tst", "")); found {
+		t.Errorf("star info should only be cached from page 1")
+	}
+
+	all, err := s.GetStarMoviesContext(context.Background(), "tst", &model.StarMoviesQuery{Page: "3", All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range all.Movies {
+		ids = append(ids, m.ID)
+	}
+	if want := []string{"TST-11", "TST-12", "TST-21", "TST-22", "TST-31", "TST-32"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("filmography = %v, want %v", ids, want)
+	}
+	if want := (model.Pagination{CurrentPage: 1, Pages: []int{1, 2, 3}}); !reflect.DeepEqual(all.Pagination, want) {
+		t.Errorf("filmography pagination = %+v, want %+v", all.Pagination, want)
+	}
+	// 第 1 页的演员信息同时写入演员缓存
+	if v, _, found := memCache.Lookup(StarCacheKey("tst", "")); !found || v.(*model.StarInfo).Birthday != "1990-08-14" {
+		t.Errorf("star info cache = %v, %v", v, found)
+	}
+
+	if _, err := s.GetStarMoviesContext(context.Background(), "missing", &model.StarMoviesQuery{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing star: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSearchPageNormalized(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	s := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors(server.URL)}
+
+	// 非法页码与缓存 key 一样按第一页请求
+	for _, page := range []string{"0", "-3", "abc"} {
+		_, _ = s.GetMoviesByKeywordAndPageContext(context.Background(), "nothing", &model.GetMoviesQuery{Page: page})
+		if got := paths[len(paths)-1]; got != "/search/nothing/1&type=1" {
+			t.Errorf("page %q requested %s, want /search/nothing/1&type=1", page, got)
+		}
+	}
+}
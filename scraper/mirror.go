@@ -0,0 +1,239 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fireinrain/javbus-api/consts"
+	"github.com/fireinrain/javbus-api/utils"
+	"github.com/go-resty/resty/v2"
+)
+
+// ErrCloudflareChallenge 镜像返回了 Cloudflare 验证页
+var ErrCloudflareChallenge = errors.New("blocked by cloudflare challenge")
+
+// mirrorFailoverStatus 这些状态码说明镜像被封或暂时不可用，需要换下一个镜像
+// 404 等属于正常结果，不切换
+var mirrorFailoverStatus = map[int]bool{
+	403: true,
+	429: true,
+	502: true,
+	503: true,
+	504: true,
+	520: true,
+	521: true,
+	522: true,
+	523: true,
+	524: true,
+	525: true,
+	526: true,
+}
+
+// cloudflareMarkers Cloudflare 验证页中的特征字符串
+var cloudflareMarkers = [][]byte{
+	[]byte("cf-browser-verification"),
+	[]byte("challenge-platform"),
+	[]byte("cf_chl_opt"),
+	[]byte("<title>Just a moment...</title>"),
+	[]byte("Attention Required! | Cloudflare"),
+}
+
+// MirrorStatus 单个镜像的状态
+type MirrorStatus struct {
+	URL          string     `json:"url"`
+	Active       bool       `json:"active"`
+	Failures     int        `json:"failures"`
+	LastError    string     `json:"lastError,omitempty"`
+	LastFailedAt *time.Time `json:"lastFailedAt,omitempty"`
+}
+
+// Mirrors JavBus 镜像域名列表
+// 请求优先使用当前镜像，失败时按顺序切换到下一个，成功的镜像成为新的当前镜像；
+// 解析结果中的链接统一改写为主域名，保证不同镜像返回的数据一致
+type Mirrors struct {
+	urls  []string
+	hosts map[string]bool
+
+	mu      sync.Mutex
+	current int
+	status  []MirrorStatus
+}
+
+// NewMirrors 创建镜像列表，第一个为主域名，重复的地址会被忽略
+func NewMirrors(urls ...string) *Mirrors {
+	m := &Mirrors{hosts: make(map[string]bool)}
+	for _, raw := range urls {
+		raw = strings.TrimRight(strings.TrimSpace(raw), "/")
+		u, err := url.Parse(raw)
+		if raw == "" || err != nil || u.Host == "" {
+			continue
+		}
+		host := strings.ToLower(u.Host)
+		if m.hosts[host] {
+			continue
+		}
+		m.hosts[host] = true
+		m.urls = append(m.urls, raw)
+		m.status = append(m.status, MirrorStatus{URL: raw})
+	}
+	if len(m.urls) == 0 {
+		m.urls = []string{consts.JavBusURL}
+		m.hosts["www.javbus.com"] = true
+		m.status = []MirrorStatus{{URL: consts.JavBusURL}}
+	}
+	m.status[0].Active = true
+	return m
+}
+
+// Primary 主域名
+func (m *Mirrors) Primary() string {
+	return m.urls[0]
+}
+
+// Current 当前使用的镜像
+func (m *Mirrors) Current() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.urls[m.current]
+}
+
+// Status 返回所有镜像的状态
+func (m *Mirrors) Status() []MirrorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]MirrorStatus(nil), m.status...)
+}
+
+// order 本次请求尝试的镜像顺序: 当前镜像优先，其余按配置顺序
+func (m *Mirrors) order() []int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]int, 0, len(m.urls))
+	for i := 0; i < len(m.urls); i++ {
+		list = append(list, (m.current+i)%len(m.urls))
+	}
+	return list
+}
+
+func (m *Mirrors) markSuccess(i int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[i].Failures = 0
+	if m.current != i {
+		log.Printf("JavBus 镜像切换: %s -> %s", m.urls[m.current], m.urls[i])
+		m.status[m.current].Active = false
+		m.status[i].Active = true
+		m.current = i
+	}
+}
+
+func (m *Mirrors) markFailure(i int, msg string) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.status[i].Failures++
+	m.status[i].LastError = msg
+	m.status[i].LastFailedAt = &now
+}
+
+// Canonical 把镜像返回的链接统一为主域名，相对路径补全为主域名下的绝对路径
+// 其他站点的链接 (例如图床) 原样返回
+func (m *Mirrors) Canonical(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	if strings.HasPrefix(raw, "//") {
+		raw = "https:" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || !m.hosts[strings.ToLower(u.Host)] {
+		return utils.FormatImageURL(raw, m.Primary())
+	}
+	return m.Primary() + u.RequestURI()
+}
+
+// Path 返回站内链接的路径 (不含开头的 /)，站外链接返回空字符串
+func (m *Mirrors) Path(href string) string {
+	href = strings.TrimSpace(href)
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if u.Host != "" && !m.hosts[strings.ToLower(u.Host)] {
+		return ""
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// blockedResponse 判断响应是否说明镜像被封 (返回原因)，正常响应返回空字符串
+func blockedResponse(resp *resty.Response) (string, error) {
+	if mirrorFailoverStatus[resp.StatusCode()] {
+		return fmt.Sprintf("status code %d", resp.StatusCode()), nil
+	}
+	if isCloudflareChallenge(resp) {
+		return ErrCloudflareChallenge.Error(), ErrCloudflareChallenge
+	}
+	return "", nil
+}
+
+func isCloudflareChallenge(resp *resty.Response) bool {
+	if resp.Header().Get("Cf-Mitigated") == "challenge" {
+		return true
+	}
+	body := resp.Body()
+	if len(body) > 64*1024 {
+		body = body[:64*1024]
+	}
+	for _, marker := range cloudflareMarkers {
+		if bytes.Contains(body, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// get 依次通过镜像请求 path (以 / 开头)，build 用于设置请求头等，base 为本次使用的镜像
+// 所有镜像都被封时返回最后一个镜像的响应 (Cloudflare 验证页返回 ErrCloudflareChallenge)
+// ctx 取消后不再尝试其他镜像，也不记为镜像失败
+func (s *JavbusScraper) get(ctx context.Context, path string, build func(req *resty.Request, base string)) (*resty.Response, string, error) {
+	var (
+		lastResp *resty.Response
+		lastBase string
+		lastErr  error
+	)
+	for _, i := range s.Mirrors.order() {
+		if err := ctx.Err(); err != nil {
+			return nil, lastBase, err
+		}
+		base := s.Mirrors.urls[i]
+		req := s.Client.R().SetContext(ctx)
+		if build != nil {
+			build(req, base)
+		}
+		resp, err := req.Get(base + path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, base, ctx.Err()
+			}
+			s.Mirrors.markFailure(i, err.Error())
+			lastResp, lastBase, lastErr = nil, base, err
+			continue
+		}
+		reason, blockErr := blockedResponse(resp)
+		if reason == "" {
+			s.Mirrors.markSuccess(i)
+			return resp, base, nil
+		}
+		s.Mirrors.markFailure(i, reason)
+		lastResp, lastBase, lastErr = resp, base, blockErr
+	}
+	return lastResp, lastBase, lastErr
+}
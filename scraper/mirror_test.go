@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+func TestMirrorsFailover(t *testing.T) {
+	blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer blocked.Close()
+	challenge := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, "<html><head><title>Just a moment...</title></head></html>")
+	}))
+	defer challenge.Close()
+	var referer string
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		referer = r.Header.Get("Referer")
+		_, _ = io.WriteString(w, "ok "+r.URL.Path)
+	}))
+	defer good.Close()
+
+	s := &JavbusScraper{
+		Client:  NewRestyClientWithPool(nil),
+		Mirrors: NewMirrors(blocked.URL, challenge.URL, good.URL),
+	}
+	resp, base, err := s.get(context.Background(), "/SSIS-001", nil)
+	if err != nil || base != good.URL || resp.String() != "ok /SSIS-001" {
+		t.Fatalf("get() = %v, %s, %v", resp, base, err)
+	}
+	if s.Mirrors.Current() != good.URL {
+		t.Errorf("Current() = %s, want %s", s.Mirrors.Current(), good.URL)
+	}
+	status := s.Mirrors.Status()
+	if status[0].Failures != 1 || status[1].LastError != ErrCloudflareChallenge.Error() || !status[2].Active {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	// 当前镜像可用时直接使用，Referer 跟随镜像
+	_, _, err = s.get(context.Background(), "/ajax/uncledatoolsbyajax.php", func(req *resty.Request, base string) {
+		req.SetHeader("Referer", base+"/SSIS-001")
+	})
+	if err != nil || referer != good.URL+"/SSIS-001" {
+		t.Errorf("referer = %s, err = %v", referer, err)
+	}
+
+	// 全部镜像都被封时返回错误
+	all := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors(blocked.URL, challenge.URL)}
+	_, _, err = all.get(context.Background(), "/", nil)
+	if !errors.Is(err, ErrCloudflareChallenge) {
+		t.Errorf("get() on blocked mirrors err = %v", err)
+	}
+}
+
+func TestMirrorsCanonical(t *testing.T) {
+	m := NewMirrors("https://www.javbus.com", "https://www.javsee.men/", "https://www.javbus.com")
+	if len(m.Status()) != 2 {
+		t.Fatalf("duplicate mirror not ignored: %+v", m.Status())
+	}
+
+	tests := map[string]string{
+		"/pics/cover/abc_b.jpg":                     "https://www.javbus.com/pics/cover/abc_b.jpg",
+		"https://www.javsee.men/pics/thumb/abc.jpg": "https://www.javbus.com/pics/thumb/abc.jpg",
+		"//www.javsee.men/pics/thumb/abc.jpg":       "https://www.javbus.com/pics/thumb/abc.jpg",
+		"https://pics.dmm.co.jp/digital/abc.jpg":    "https://pics.dmm.co.jp/digital/abc.jpg",
+		"":                                          "",
+	}
+	for raw, want := range tests {
+		if got := m.Canonical(raw); got != want {
+			t.Errorf("Canonical(%q) = %q, want %q", raw, got, want)
+		}
+	}
+
+	links := []struct{ href, prefix, want string }{
+		{"https://www.javsee.men/studio/7q", "studio", "7q"},
+		{"https://www.javbus.com/uncensored/studio/3n", "studio", "uncensored/3n"},
+		{"/genre/4o", "genre", "4o"},
+		{"https://www.javsee.men/star/okq", "star", "okq"},
+		{"https://example.com/star/okq", "star", ""},
+		{"https://www.javbus.com/label/abc", "series", ""},
+	}
+	for _, l := range links {
+		if got := linkID(m.Path(l.href), l.prefix); got != l.want {
+			t.Errorf("linkID(%q, %q) = %q, want %q", l.href, l.prefix, got, l.want)
+		}
+	}
+}
+
+func TestMirrorsCancel(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+	var hits int
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer good.Close()
+
+	s := &JavbusScraper{
+		Client:  NewRestyClientWithPool(nil),
+		Mirrors: NewMirrors(slow.URL, good.URL),
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := s.get(ctx, "/", nil)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > time.Second {
+		t.Fatalf("get() err = %v after %v", err, time.Since(start))
+	}
+	// 客户端取消不是镜像的问题，不切换镜像也不记录失败
+	if hits != 0 || s.Mirrors.Current() != slow.URL || s.Mirrors.Status()[0].Failures != 0 {
+		t.Errorf("hits = %d, status = %+v", hits, s.Mirrors.Status())
+	}
+}
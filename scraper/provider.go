@@ -0,0 +1,177 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// JavbusProviderName JavBus 数据源在注册表中的名称，也是默认数据源
+const JavbusProviderName = "javbus"
+
+// Provider 影片元数据源接口
+// 任何站点只要实现了列表、搜索、详情、演员、磁力这几个方法，就可以注册进来供路由使用
+type Provider interface {
+	// Name 数据源名称，作为注册表的 key，对应请求参数 ?provider=
+	Name() string
+	GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesPage, error)
+	GetMoviesByKeywordAndPage(keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error)
+	GetMovieDetail(id string) (*model.MovieDetail, error)
+	GetStarInfo(starId string, movieType string) (*model.StarInfo, error)
+	GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error)
+}
+
+// ImageFetcher 可选接口，数据源可以下载自己站点上的图片 (需要带 Referer 绕过防盗链)
+type ImageFetcher interface {
+	GetImage(url string, referer string) ([]byte, error)
+}
+
+// ContextProvider 可选接口，支持 context 的数据源在客户端断开或超时后停止请求上游
+type ContextProvider interface {
+	Provider
+	GetMoviesByPageContext(ctx context.Context, q *model.GetMoviesQuery) (*model.MoviesPage, error)
+	GetMoviesByKeywordAndPageContext(ctx context.Context, keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error)
+	GetMovieDetailContext(ctx context.Context, id string) (*model.MovieDetail, error)
+	GetStarInfoContext(ctx context.Context, starId string, movieType string) (*model.StarInfo, error)
+	GetMovieMagnetsContext(ctx context.Context, movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error)
+}
+
+// ContextImageFetcher 可选接口，支持 context 的图片下载
+type ContextImageFetcher interface {
+	GetImageContext(ctx context.Context, url string, referer string) ([]byte, error)
+}
+
+// GenreProvider 可选接口，数据源可以返回类别目录
+type GenreProvider interface {
+	GetGenresContext(ctx context.Context, movieType model.MovieType) (*model.GenreCatalog, error)
+}
+
+// StarListProvider 可选接口，数据源可以列出和搜索演员
+type StarListProvider interface {
+	GetStarsContext(ctx context.Context, q *model.GetStarsQuery) (*model.StarsPage, error)
+	SearchStarsContext(ctx context.Context, keyword string, q *model.GetStarsQuery) (*model.SearchStarsPage, error)
+}
+
+// StarMoviesProvider 可选接口，数据源可以返回演员的作品列表
+type StarMoviesProvider interface {
+	GetStarMoviesContext(ctx context.Context, starId string, q *model.StarMoviesQuery) (*model.StarMoviesPage, error)
+}
+
+// MovieLinker 可选接口，数据源可以给出影片在站点上的详情页地址 (Torznab 的 comments)
+type MovieLinker interface {
+	MovieURL(id string) string
+}
+
+// 编译期检查 JavbusScraper 是否实现了 Provider
+var (
+	_ Provider            = (*JavbusScraper)(nil)
+	_ ImageFetcher        = (*JavbusScraper)(nil)
+	_ ContextProvider     = (*JavbusScraper)(nil)
+	_ ContextImageFetcher = (*JavbusScraper)(nil)
+	_ GenreProvider       = (*JavbusScraper)(nil)
+	_ StarListProvider    = (*JavbusScraper)(nil)
+	_ StarMoviesProvider  = (*JavbusScraper)(nil)
+	_ MovieLinker         = (*JavbusScraper)(nil)
+)
+
+// WithContext 返回绑定了 ctx 的数据源，各方法调用对应的 Context 版本
+// 数据源没有实现 ContextProvider 时原样返回；原数据源支持下载图片时返回值同样实现 ImageFetcher
+func WithContext(ctx context.Context, p Provider) Provider {
+	cp, ok := p.(ContextProvider)
+	if !ok {
+		return p
+	}
+	bound := contextProvider{ctx: ctx, p: cp}
+	if fetcher, ok := p.(ContextImageFetcher); ok {
+		return contextImageProvider{contextProvider: bound, fetcher: fetcher}
+	}
+	if _, ok := p.(ImageFetcher); ok {
+		return contextImageProvider{contextProvider: bound}
+	}
+	return bound
+}
+
+type contextProvider struct {
+	ctx context.Context
+	p   ContextProvider
+}
+
+func (b contextProvider) Name() string {
+	return b.p.Name()
+}
+
+func (b contextProvider) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	return b.p.GetMoviesByPageContext(b.ctx, q)
+}
+
+func (b contextProvider) GetMoviesByKeywordAndPage(keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
+	return b.p.GetMoviesByKeywordAndPageContext(b.ctx, keyword, q)
+}
+
+func (b contextProvider) GetMovieDetail(id string) (*model.MovieDetail, error) {
+	return b.p.GetMovieDetailContext(b.ctx, id)
+}
+
+func (b contextProvider) GetStarInfo(starId string, movieType string) (*model.StarInfo, error) {
+	return b.p.GetStarInfoContext(b.ctx, starId, movieType)
+}
+
+func (b contextProvider) GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error) {
+	return b.p.GetMovieMagnetsContext(b.ctx, movieId, gid, uc, sortBy, sortOrder)
+}
+
+// contextImageProvider 原数据源支持下载图片时使用，fetcher 为空时调用不带 context 的 GetImage
+type contextImageProvider struct {
+	contextProvider
+	fetcher ContextImageFetcher
+}
+
+func (b contextImageProvider) GetImage(url string, referer string) ([]byte, error) {
+	if b.fetcher != nil {
+		return b.fetcher.GetImageContext(b.ctx, url, referer)
+	}
+	return b.p.(ImageFetcher).GetImage(url, referer)
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]Provider)
+)
+
+// RegisterProvider 注册数据源，同名数据源会被覆盖
+func RegisterProvider(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// GetProvider 按名称获取数据源，name 为空时返回默认的 JavBus 数据源
+func GetProvider(name string) (Provider, error) {
+	if name == "" {
+		name = JavbusProviderName
+	}
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider: %s", name)
+	}
+	return p, nil
+}
+
+// ProviderNames 返回所有已注册数据源的名称 (按字母排序)
+func ProviderNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
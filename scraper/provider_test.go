@@ -0,0 +1,36 @@
+package scraper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// plainProvider 只实现了 Provider 的数据源
+type plainProvider struct{ Provider }
+
+func TestWithContext(t *testing.T) {
+	plain := plainProvider{}
+	if p := WithContext(context.Background(), plain); p != Provider(plain) {
+		t.Errorf("WithContext() on plain provider = %T, want unchanged", p)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s := &JavbusScraper{Client: NewRestyClientWithPool(nil), Mirrors: NewMirrors("http://127.0.0.1:1")}
+	p := WithContext(ctx, s)
+	if _, ok := p.(ImageFetcher); !ok {
+		t.Error("bound provider should keep ImageFetcher")
+	}
+	if p.Name() != JavbusProviderName {
+		t.Errorf("Name() = %s", p.Name())
+	}
+	// 已取消的 ctx 不会请求上游，也不会记为镜像失败
+	if _, err := p.GetMoviesByPage(&model.GetMoviesQuery{Page: "99999"}); err != context.Canceled {
+		t.Errorf("GetMoviesByPage() err = %v, want context.Canceled", err)
+	}
+	if s.Mirrors.Status()[0].Failures != 0 {
+		t.Errorf("mirror status = %+v", s.Mirrors.Status())
+	}
+}
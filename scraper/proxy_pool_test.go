@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/config"
+)
+
+// newTestProxy 返回一个简单的 HTTP 正向代理，记录经过的请求数
+func newTestProxy(t *testing.T, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		_, _ = io.WriteString(w, "via proxy "+r.URL.Host)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+// deadProxyURL 返回一个无法连接的代理地址
+func deadProxyURL(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return "http://" + addr
+}
+
+func TestProxyPoolFailover(t *testing.T) {
+	good, goodHits := newTestProxy(t, http.StatusOK)
+	pool, err := NewProxyPool([]string{deadProxyURL(t), good.URL}, 2, good.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: pool}
+
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get("http://www.javbus.com/SSIS-001")
+		if err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+		drainAndClose(resp)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d: status = %d", i, resp.StatusCode)
+		}
+	}
+	if goodHits.Load() != 4 {
+		t.Errorf("good proxy hits = %d, want 4", goodHits.Load())
+	}
+
+	status := pool.Status()
+	if status[0].Healthy || status[0].Failures < 2 || status[0].LastError == "" {
+		t.Errorf("dead proxy status = %+v", status[0])
+	}
+	if !status[1].Healthy || pool.Healthy() != 1 {
+		t.Errorf("good proxy status = %+v, healthy = %d", status[1], pool.Healthy())
+	}
+
+	// 不可用的代理健康检查失败后依然保持不可用
+	pool.CheckUnhealthy(context.Background())
+	if pool.Status()[0].Healthy || pool.Status()[0].LastCheckedAt == nil {
+		t.Errorf("dead proxy after health check = %+v", pool.Status()[0])
+	}
+}
+
+func TestProxyPoolRotation(t *testing.T) {
+	first, firstHits := newTestProxy(t, http.StatusOK)
+	second, secondHits := newTestProxy(t, http.StatusOK)
+	pool, err := NewProxyPool([]string{first.URL, second.URL}, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: pool}
+	for i := 0; i < 4; i++ {
+		resp, err := client.Get("http://www.javbus.com/")
+		if err != nil {
+			t.Fatal(err)
+		}
+		drainAndClose(resp)
+	}
+	if firstHits.Load() != 2 || secondHits.Load() != 2 {
+		t.Errorf("hits = %d / %d, want 2 / 2", firstHits.Load(), secondHits.Load())
+	}
+}
+
+func TestProxyPoolBlockedStatus(t *testing.T) {
+	blocked, _ := newTestProxy(t, http.StatusForbidden)
+	good, goodHits := newTestProxy(t, http.StatusOK)
+	pool, err := NewProxyPool([]string{blocked.URL, good.URL}, 1, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := (&http.Client{Transport: pool}).Get("http://www.javbus.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	drainAndClose(resp)
+	if resp.StatusCode != http.StatusOK || goodHits.Load() != 1 {
+		t.Errorf("status = %d, good hits = %d", resp.StatusCode, goodHits.Load())
+	}
+	if pool.Status()[0].Healthy {
+		t.Errorf("blocked proxy should be unhealthy: %+v", pool.Status()[0])
+	}
+}
+
+func TestProxyURLs(t *testing.T) {
+	urls := ProxyURLs(config.ProxyConfig{
+		Proxies:     []string{"http://127.0.0.1:7890", "socks5://127.0.0.1:1080"},
+		HttpProxy:   "http://127.0.0.1:7890",
+		Socks5Proxy: "socks5://127.0.0.1:1081",
+	})
+	want := []string{"http://127.0.0.1:7890", "socks5://127.0.0.1:1080", "socks5://127.0.0.1:1081"}
+	if len(urls) != len(want) {
+		t.Fatalf("ProxyURLs() = %v, want %v", urls, want)
+	}
+	for i := range want {
+		if urls[i] != want[i] {
+			t.Errorf("ProxyURLs()[%d] = %s, want %s", i, urls[i], want[i])
+		}
+	}
+
+	if _, err := NewProxyPool([]string{"://bad"}, 0, ""); err == nil {
+		t.Error("NewProxyPool() with invalid url should fail")
+	}
+}
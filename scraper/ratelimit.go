@@ -0,0 +1,291 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/metrics"
+	"golang.org/x/time/rate"
+)
+
+// BackoffError 域名处于退避期，请求未发出直接失败
+type BackoffError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *BackoffError) Error() string {
+	return fmt.Sprintf("%s is backing off until %s", e.Host, e.Until.Format(time.RFC3339))
+}
+
+// Unwrap 使 errors.Is(err, ErrRateLimited) 成立
+func (e *BackoffError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// RateLimiterStatus 限流器当前状态，用于健康检查
+type RateLimiterStatus struct {
+	RequestsPerSecond        float64             `json:"requestsPerSecond"` // 0 表示不限制
+	Burst                    int                 `json:"burst"`
+	PerHostRequestsPerSecond float64             `json:"perHostRequestsPerSecond"`
+	PerHostBurst             int                 `json:"perHostBurst"`
+	MaxConcurrent            int                 `json:"maxConcurrent"` // 0 表示不限制
+	InFlight                 int64               `json:"inFlight"`      // 正在进行的上游请求
+	Waiting                  int64               `json:"waiting"`       // 正在等待限流的请求
+	Hosts                    []HostLimiterStatus `json:"hosts"`
+}
+
+// HostLimiterStatus 单个域名的限流状态
+type HostLimiterStatus struct {
+	Host         string     `json:"host"`
+	Requests     int64      `json:"requests"`
+	Backoffs     int64      `json:"backoffs"` // 因 403/429/503 进入退避的次数
+	Failures     int        `json:"failures"` // 连续 403/429/503 次数
+	LastStatus   int        `json:"lastStatus,omitempty"`
+	BackoffUntil *time.Time `json:"backoffUntil,omitempty"` // 退避中时才有值
+}
+
+// RateLimiter 上游请求的客户端限流: 全局和每个域名的令牌桶、最大并发数、随机等待，
+// 以及上游返回 403/429/503 时对该域名的指数退避
+type RateLimiter struct {
+	cfg    config.RateLimitConfig
+	global *rate.Limiter // 不限制时为 nil
+	slots  chan struct{} // 不限制并发时为 nil
+
+	inFlight atomic.Int64
+	waiting  atomic.Int64
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter
+}
+
+type hostLimiter struct {
+	limiter *rate.Limiter // 不限制时为 nil
+
+	// 以下字段由 RateLimiter.mu 保护
+	requests     int64
+	backoffs     int64
+	failures     int
+	lastStatus   int
+	backoffUntil time.Time
+}
+
+// NewRateLimiter 根据配置创建限流器
+func NewRateLimiter(cfg config.RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{cfg: cfg, hosts: make(map[string]*hostLimiter)}
+	if cfg.RequestsPerSecond > 0 {
+		l.global = rate.NewLimiter(rate.Limit(cfg.RequestsPerSecond), max(cfg.Burst, 1))
+	}
+	if cfg.MaxConcurrent > 0 {
+		l.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return l
+}
+
+// Wrap 返回经过限流的 RoundTripper
+func (l *RateLimiter) Wrap(next http.RoundTripper) http.RoundTripper {
+	return &rateLimitTransport{limiter: l, next: next}
+}
+
+type rateLimitTransport struct {
+	limiter *RateLimiter
+	next    http.RoundTripper
+}
+
+// RoundTrip 依次检查退避、并发数、全局和域名令牌桶、随机等待，然后发出请求
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	l := t.limiter
+	ctx := req.Context()
+	host := req.URL.Host
+	h := l.host(host)
+
+	// 1. 退避中的域名直接失败，不占用并发和令牌
+	if until := l.backoffUntil(h); !until.IsZero() {
+		return nil, &BackoffError{Host: host, Until: until}
+	}
+
+	start := time.Now()
+	l.waiting.Add(1)
+	release, err := l.acquire(ctx, h)
+	l.waiting.Add(-1)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	metrics.RateLimitWaitSeconds.WithLabelValues(host).Observe(time.Since(start).Seconds())
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	l.record(host, h, resp)
+	return resp, nil
+}
+
+// acquire 获取并发名额并等待令牌和随机延迟，返回释放并发名额的函数
+func (l *RateLimiter) acquire(ctx context.Context, h *hostLimiter) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		l.inFlight.Add(-1)
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+	l.inFlight.Add(1)
+
+	if err := l.wait(ctx, h); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+func (l *RateLimiter) wait(ctx context.Context, h *hostLimiter) error {
+	if l.global != nil {
+		if err := l.global.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if h.limiter != nil {
+		if err := h.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if d := l.jitter(); d > 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// jitter 在 [JitterMinMs, JitterMaxMs] 之间随机取一个等待时间
+func (l *RateLimiter) jitter() time.Duration {
+	lo, hi := l.cfg.JitterMinMs, l.cfg.JitterMaxMs
+	if hi <= 0 {
+		return 0
+	}
+	ms := lo
+	if hi > lo {
+		ms += rand.IntN(hi - lo + 1)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+func (l *RateLimiter) host(host string) *hostLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h, ok := l.hosts[host]
+	if !ok {
+		h = &hostLimiter{}
+		if l.cfg.PerHostRequestsPerSecond > 0 {
+			h.limiter = rate.NewLimiter(rate.Limit(l.cfg.PerHostRequestsPerSecond), max(l.cfg.PerHostBurst, 1))
+		}
+		l.hosts[host] = h
+	}
+	return h
+}
+
+// backoffUntil 域名处于退避期时返回结束时间，否则返回零值
+func (l *RateLimiter) backoffUntil(h *hostLimiter) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().Before(h.backoffUntil) {
+		return h.backoffUntil
+	}
+	return time.Time{}
+}
+
+// record 记录响应状态码，403/429/503 时让该域名进入退避，
+// 退避时间从 BackoffSeconds 开始随连续次数翻倍，上游给出更长的 Retry-After 时以其为准，
+// 两者都不超过 MaxBackoffSeconds (为 0 时不设上限)
+func (l *RateLimiter) record(host string, h *hostLimiter, resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	h.requests++
+	h.lastStatus = resp.StatusCode
+
+	switch resp.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		h.failures = 0
+		return
+	}
+	h.failures++
+	if l.cfg.BackoffSeconds <= 0 {
+		return
+	}
+
+	backoff := time.Duration(l.cfg.BackoffSeconds) * time.Second
+	limit := time.Duration(l.cfg.MaxBackoffSeconds) * time.Second
+	if limit <= 0 {
+		limit = math.MaxInt64 / 2 // 不设上限，只防止翻倍溢出
+	}
+	for i := 1; i < h.failures && backoff < limit; i++ {
+		backoff *= 2
+	}
+	if ra := retryAfter(resp.Header.Get("Retry-After")); ra > backoff {
+		backoff = ra
+	}
+	backoff = min(backoff, limit)
+
+	if until := time.Now().Add(backoff); until.After(h.backoffUntil) {
+		h.backoffUntil = until
+	}
+	h.backoffs++
+	metrics.UpstreamBackoffsTotal.WithLabelValues(host, strconv.Itoa(resp.StatusCode)).Inc()
+}
+
+// Status 限流器当前状态，域名按名称排序
+func (l *RateLimiter) Status() RateLimiterStatus {
+	status := RateLimiterStatus{
+		RequestsPerSecond:        l.cfg.RequestsPerSecond,
+		Burst:                    l.cfg.Burst,
+		PerHostRequestsPerSecond: l.cfg.PerHostRequestsPerSecond,
+		PerHostBurst:             l.cfg.PerHostBurst,
+		MaxConcurrent:            l.cfg.MaxConcurrent,
+		InFlight:                 l.inFlight.Load(),
+		Waiting:                  l.waiting.Load(),
+		Hosts:                    []HostLimiterStatus{},
+	}
+
+	now := time.Now()
+	l.mu.Lock()
+	for host, h := range l.hosts {
+		hs := HostLimiterStatus{
+			Host:       host,
+			Requests:   h.requests,
+			Backoffs:   h.backoffs,
+			Failures:   h.failures,
+			LastStatus: h.lastStatus,
+		}
+		if now.Before(h.backoffUntil) {
+			until := h.backoffUntil
+			hs.BackoffUntil = &until
+		}
+		status.Hosts = append(status.Hosts, hs)
+	}
+	l.mu.Unlock()
+
+	sort.Slice(status.Hosts, func(i, j int) bool { return status.Hosts[i].Host < status.Hosts[j].Host })
+	return status
+}
@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fireinrain/javbus-api/config"
+)
+
+func TestRateLimiterBackoff(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	limiter := NewRateLimiter(config.RateLimitConfig{BackoffSeconds: 30, MaxBackoffSeconds: 600})
+	client := NewRestyClientWithLimiter(nil, limiter)
+
+	resp, err := client.R().Get(srv.URL + "/ABP-123")
+	if err := upstreamError(resp, srv.URL, err); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("first request: err = %v, want ErrRateLimited", err)
+	}
+	n := hits.Load()
+
+	// 退避期内请求不再发出，也不触发 resty 重试
+	resp, err = client.R().Get(srv.URL + "/ABP-123")
+	var backoff *BackoffError
+	if !errors.As(err, &backoff) {
+		t.Fatalf("second request: err = %v, want BackoffError", err)
+	}
+	if hits.Load() != n {
+		t.Fatalf("upstream hits = %d, want %d", hits.Load(), n)
+	}
+	e := upstreamError(resp, srv.URL, err)
+	var upstream *UpstreamError
+	if !errors.Is(e, ErrRateLimited) || !errors.As(e, &upstream) || upstream.RetryAfter < 100*time.Second {
+		t.Fatalf("upstreamError = %#v, want ErrRateLimited honouring Retry-After", e)
+	}
+
+	status := limiter.Status()
+	if len(status.Hosts) != 1 || status.Hosts[0].BackoffUntil == nil || status.Hosts[0].LastStatus != http.StatusTooManyRequests {
+		t.Fatalf("status = %+v", status)
+	}
+}
+
+func TestRateLimiterBackoffGrowth(t *testing.T) {
+	limiter := NewRateLimiter(config.RateLimitConfig{BackoffSeconds: 10, MaxBackoffSeconds: 25})
+	h := limiter.host("www.javbus.com")
+	resp := &http.Response{StatusCode: http.StatusServiceUnavailable, Header: http.Header{}}
+
+	for i, want := range []time.Duration{10 * time.Second, 20 * time.Second, 25 * time.Second, 25 * time.Second} {
+		h.backoffUntil = time.Time{}
+		limiter.record("www.javbus.com", h, resp)
+		got := time.Until(h.backoffUntil)
+		if got > want || got < want-time.Second {
+			t.Fatalf("failure %d: backoff = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// 正常响应后重新计数
+	limiter.record("www.javbus.com", h, &http.Response{StatusCode: http.StatusOK, Header: http.Header{}})
+	if h.failures != 0 {
+		t.Fatalf("failures = %d after success, want 0", h.failures)
+	}
+
+	// Retry-After 同样不超过上限
+	h.backoffUntil = time.Time{}
+	limiter.record("www.javbus.com", h, &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3600"}}})
+	if got := time.Until(h.backoffUntil); got > 25*time.Second {
+		t.Fatalf("Retry-After backoff = %v, want at most 25s", got)
+	}
+
+	// MaxBackoffSeconds 为 0 时不设上限
+	limiter = NewRateLimiter(config.RateLimitConfig{BackoffSeconds: 10})
+	h = limiter.host("www.javbus.com")
+	for i, want := range []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second} {
+		limiter.record("www.javbus.com", h, resp)
+		got := time.Until(h.backoffUntil)
+		if got > want || got < want-time.Second {
+			t.Fatalf("uncapped failure %d: backoff = %v, want %v", i+1, got, want)
+		}
+	}
+}
+
+func TestRateLimiterConcurrency(t *testing.T) {
+	var current, peak atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		current.Add(-1)
+	}))
+	defer srv.Close()
+
+	limiter := NewRateLimiter(config.RateLimitConfig{MaxConcurrent: 2, JitterMaxMs: 5})
+	client := &http.Client{Transport: limiter.Wrap(http.DefaultTransport)}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(srv.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			drainAndClose(resp)
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > 2 {
+		t.Fatalf("peak concurrency = %d, want <= 2", p)
+	}
+	if s := limiter.Status(); s.InFlight != 0 || s.Waiting != 0 || s.Hosts[0].Requests != 8 {
+		t.Fatalf("status = %+v", s)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	// 每秒 1 个请求，第二个请求需要等待令牌
+	limiter := NewRateLimiter(config.RateLimitConfig{RequestsPerSecond: 1, Burst: 1})
+	client := &http.Client{Transport: limiter.Wrap(http.DefaultTransport)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	drainAndClose(resp)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	start := time.Now()
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("expected error for cancelled request")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("cancelled request waited %v", time.Since(start))
+	}
+}
@@ -0,0 +1,172 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+)
+
+// MagnetAvailableHandler 监控的影片满足条件时的回调
+type MagnetAvailableHandler func(watch cachedb.MagnetWatch, magnets []model.Magnet)
+
+// MagnetWatcher 磁力监控，定期检查尚无 (高清 / 字幕) 磁力链接的影片
+type MagnetWatcher struct {
+	provider scraper.Provider
+
+	mu       sync.Mutex
+	handlers []MagnetAvailableHandler
+}
+
+// NewMagnetWatcher 创建磁力监控
+func NewMagnetWatcher(provider scraper.Provider) *MagnetWatcher {
+	return &MagnetWatcher{provider: provider}
+}
+
+// OnAvailable 注册回调，需要在调度启动前调用
+func (w *MagnetWatcher) OnAvailable(handler MagnetAvailableHandler) {
+	w.handlers = append(w.handlers, handler)
+}
+
+// Watch 新建监控并立即检查一次
+// 创建时就已满足条件的影片直接标记为已满足，不发送通知
+func (w *MagnetWatcher) Watch(movieID, notifyOn string) (*cachedb.MagnetWatch, error) {
+	if notifyOn == "" {
+		notifyOn = cachedb.WatchNotifyAny
+	}
+	existing, err := cachedb.FindMagnetWatch(movieID, notifyOn)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return existing, nil
+	}
+
+	detail, err := w.provider.GetMovieDetail(movieID)
+	if err != nil {
+		return nil, err
+	}
+	watch := &cachedb.MagnetWatch{
+		MovieID:  detail.ID,
+		NotifyOn: notifyOn,
+		Title:    detail.Title,
+		Img:      detail.Img,
+		Date:     detail.Date,
+		GID:      detail.GID,
+		UC:       detail.UC,
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	magnets, err := w.fetch(watch)
+	if err != nil {
+		watch.LastError = err.Error()
+	} else {
+		w.apply(watch, magnets)
+		watch.Fulfilled = satisfied(watch)
+	}
+	if err := cachedb.SaveMagnetWatch(watch); err != nil {
+		return nil, err
+	}
+	return watch, nil
+}
+
+// CheckAll 检查所有尚未满足条件的监控
+func (w *MagnetWatcher) CheckAll(ctx context.Context) {
+	pending := false
+	watches, err := cachedb.ListMagnetWatches(&pending)
+	if err != nil {
+		log.Printf("读取磁力监控失败: %v", err)
+		return
+	}
+	for i := range watches {
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := w.Check(&watches[i]); err != nil {
+			log.Printf("检查磁力监控 %s 失败: %v", watches[i].MovieID, err)
+		}
+	}
+}
+
+// Check 检查单个监控，条件由不满足变为满足时触发回调，返回当前的磁力链接
+func (w *MagnetWatcher) Check(watch *cachedb.MagnetWatch) ([]model.Magnet, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	magnets, err := w.fetch(watch)
+	if err != nil {
+		watch.LastError = err.Error()
+		if saveErr := cachedb.SaveMagnetWatch(watch); saveErr != nil {
+			log.Printf("更新磁力监控失败 %d: %v", watch.ID, saveErr)
+		}
+		return nil, err
+	}
+
+	before := watch.Fulfilled
+	w.apply(watch, magnets)
+	fulfilled := !before && satisfied(watch)
+	if fulfilled {
+		now := time.Now()
+		watch.Fulfilled = true
+		watch.NotifiedAt = &now
+	}
+	if err := cachedb.SaveMagnetWatch(watch); err != nil {
+		return nil, err
+	}
+
+	if fulfilled {
+		log.Printf("影片 %s 已有满足条件 (%s) 的磁力链接", watch.MovieID, watch.NotifyOn)
+		for _, handler := range w.handlers {
+			handler(*watch, magnets)
+		}
+	}
+	return magnets, nil
+}
+
+// fetch 跳过缓存获取最新的磁力链接
+func (w *MagnetWatcher) fetch(watch *cachedb.MagnetWatch) ([]model.Magnet, error) {
+	if watch.GID == "" {
+		detail, err := w.provider.GetMovieDetail(watch.MovieID)
+		if err != nil {
+			return nil, err
+		}
+		watch.GID, watch.UC = detail.GID, detail.UC
+	}
+	if watch.GID == "" {
+		return nil, fmt.Errorf("movie %s has no gid", watch.MovieID)
+	}
+
+	scraper.InvalidateMagnets(watch.MovieID, watch.GID, watch.UC, "", "")
+	return w.provider.GetMovieMagnets(watch.MovieID, watch.GID, watch.UC, "", "")
+}
+
+// apply 根据磁力链接更新监控状态
+func (w *MagnetWatcher) apply(watch *cachedb.MagnetWatch, magnets []model.Magnet) {
+	now := time.Now()
+	watch.LastCheckedAt = &now
+	watch.LastError = ""
+	watch.MagnetCount = len(magnets)
+	watch.HasHD = false
+	watch.HasSubtitle = false
+	for _, m := range magnets {
+		watch.HasHD = watch.HasHD || m.IsHD
+		watch.HasSubtitle = watch.HasSubtitle || m.HasSubtitle
+	}
+}
+
+func satisfied(watch *cachedb.MagnetWatch) bool {
+	switch watch.NotifyOn {
+	case cachedb.WatchNotifyHD:
+		return watch.HasHD
+	case cachedb.WatchNotifySubtitle:
+		return watch.HasSubtitle
+	default:
+		return watch.MagnetCount > 0
+	}
+}
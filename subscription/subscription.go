@@ -0,0 +1,191 @@
+package subscription
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/model"
+	"github.com/fireinrain/javbus-api/scraper"
+)
+
+// NewMoviesHandler 订阅发现新片时的回调
+type NewMoviesHandler func(sub cachedb.Subscription, movies []model.Movie)
+
+// Service 订阅检查服务
+// 定期拉取订阅条件对应的列表页，与数据库中已记录的影片对比，找出新片
+type Service struct {
+	provider scraper.Provider
+	pages    int
+
+	// 同一时间只执行一次检查，避免定时任务和手动检查重复请求
+	mu       sync.Mutex
+	handlers []NewMoviesHandler
+}
+
+// NewService 创建订阅服务，pages 为每次检查的最大页数
+func NewService(provider scraper.Provider, pages int) *Service {
+	if pages < 1 {
+		pages = 1
+	}
+	return &Service{provider: provider, pages: pages}
+}
+
+// OnNewMovies 注册新片回调，需要在调度启动前调用
+func (s *Service) OnNewMovies(handler NewMoviesHandler) {
+	s.handlers = append(s.handlers, handler)
+}
+
+// CheckAll 依次检查所有订阅，ctx 取消时提前结束
+func (s *Service) CheckAll(ctx context.Context) {
+	subs, err := cachedb.ListSubscriptions()
+	if err != nil {
+		log.Printf("读取订阅失败: %v", err)
+		return
+	}
+
+	total := 0
+	for i := range subs {
+		if ctx.Err() != nil {
+			return
+		}
+		movies, err := s.Check(&subs[i])
+		if err != nil {
+			log.Printf("检查订阅 %s=%s 失败: %v", subs[i].FilterType, subs[i].FilterValue, err)
+			continue
+		}
+		total += len(movies)
+	}
+	if len(subs) > 0 {
+		log.Printf("订阅检查完成: %d 个订阅, %d 部新片", len(subs), total)
+	}
+}
+
+// Check 检查单个订阅，返回本次发现的新片
+// 首次检查时列表中已有的影片只记录为基线，不作为新片返回
+func (s *Service) Check(sub *cachedb.Subscription) ([]model.Movie, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 重新读取订阅，调用方持有的可能是检查前的旧数据 (例如首次检查已在后台完成)
+	latest, err := cachedb.GetSubscription(sub.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("subscription %d not found", sub.ID)
+	}
+	*sub = *latest
+
+	movies, err := s.fetch(sub)
+	now := time.Now()
+	sub.LastCheckedAt = &now
+	if err != nil {
+		sub.LastError = err.Error()
+		if saveErr := cachedb.SaveSubscription(sub); saveErr != nil {
+			log.Printf("更新订阅失败 %d: %v", sub.ID, saveErr)
+		}
+		return nil, err
+	}
+	sub.LastError = ""
+
+	baseline := !sub.Initialized
+	records := make([]cachedb.SubscriptionMovie, 0, len(movies))
+	for _, m := range movies {
+		records = append(records, cachedb.SubscriptionMovie{
+			SubscriptionID: sub.ID,
+			MovieID:        m.ID,
+			Title:          m.Title,
+			Img:            m.Img,
+			Date:           m.Date,
+			Tags:           m.Tags,
+			Baseline:       baseline,
+			FoundAt:        now,
+		})
+	}
+	if err := cachedb.AddSubscriptionMovies(records); err != nil {
+		return nil, err
+	}
+
+	sub.Initialized = true
+	if !baseline && len(movies) > 0 {
+		sub.LastNewAt = &now
+	}
+	if err := cachedb.SaveSubscription(sub); err != nil {
+		return nil, err
+	}
+	if err := fillCount(sub); err != nil {
+		log.Printf("统计订阅新片失败 %d: %v", sub.ID, err)
+	}
+
+	if baseline {
+		log.Printf("订阅 %s=%s 初始化完成, 记录 %d 部影片", sub.FilterType, sub.FilterValue, len(movies))
+		return []model.Movie{}, nil
+	}
+	if len(movies) == 0 {
+		return []model.Movie{}, nil
+	}
+
+	log.Printf("订阅 %s=%s 发现 %d 部新片", sub.FilterType, sub.FilterValue, len(movies))
+	for _, handler := range s.handlers {
+		handler(*sub, movies)
+	}
+	return movies, nil
+}
+
+// fetch 拉取列表页，返回尚未记录过的影片
+// 列表按发布时间倒序，某一页全部是已记录的影片时不再继续翻页
+func (s *Service) fetch(sub *cachedb.Subscription) ([]model.Movie, error) {
+	var unseen []model.Movie
+	seen := make(map[string]bool)
+
+	for page := 1; page <= s.pages; page++ {
+		result, err := s.provider.GetMoviesByPage(&model.GetMoviesQuery{
+			Page:        strconv.Itoa(page),
+			Type:        model.MovieType(sub.Type),
+			Magnet:      model.MagnetType(sub.Magnet),
+			FilterType:  model.FilterType(sub.FilterType),
+			FilterValue: sub.FilterValue,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		ids := make([]string, 0, len(result.Movies))
+		for _, m := range result.Movies {
+			ids = append(ids, m.ID)
+		}
+		known, err := cachedb.KnownSubscriptionMovieIDs(sub.ID, ids)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh := 0
+		for _, m := range result.Movies {
+			if m.ID == "" || known[m.ID] || seen[m.ID] {
+				continue
+			}
+			seen[m.ID] = true
+			unseen = append(unseen, m)
+			fresh++
+		}
+
+		if !result.Pagination.HasNextPage || (sub.Initialized && fresh == 0) {
+			break
+		}
+	}
+	return unseen, nil
+}
+
+func fillCount(sub *cachedb.Subscription) error {
+	fresh, err := cachedb.GetSubscription(sub.ID)
+	if err != nil || fresh == nil {
+		return err
+	}
+	sub.NewCount = fresh.NewCount
+	return nil
+}
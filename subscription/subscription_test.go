@@ -0,0 +1,175 @@
+package subscription
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// fakeProvider 每页返回固定的影片列表
+type fakeProvider struct {
+	pages   map[string][]model.Movie
+	magnets []model.Magnet
+	calls   int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) GetMoviesByPage(q *model.GetMoviesQuery) (*model.MoviesPage, error) {
+	p.calls++
+	movies := p.pages[q.Page]
+	_, hasNext := p.pages[nextPage(q.Page)]
+	return &model.MoviesPage{Movies: movies, Pagination: model.Pagination{HasNextPage: hasNext}}, nil
+}
+
+func (p *fakeProvider) GetMoviesByKeywordAndPage(keyword string, q *model.GetMoviesQuery) (*model.SearchMoviesPage, error) {
+	return &model.SearchMoviesPage{}, nil
+}
+
+func (p *fakeProvider) GetMovieDetail(id string) (*model.MovieDetail, error) {
+	return &model.MovieDetail{ID: id, Title: id + " title", GID: "123", UC: "0"}, nil
+}
+
+func (p *fakeProvider) GetStarInfo(starId string, movieType string) (*model.StarInfo, error) {
+	return &model.StarInfo{ID: starId}, nil
+}
+
+func (p *fakeProvider) GetMovieMagnets(movieId, gid, uc, sortBy, sortOrder string) ([]model.Magnet, error) {
+	return p.magnets, nil
+}
+
+func nextPage(page string) string {
+	return string(rune(page[0] + 1))
+}
+
+func movies(ids ...string) []model.Movie {
+	list := make([]model.Movie, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, model.Movie{ID: id, Title: id + " title"})
+	}
+	return list
+}
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	db, err := cachedb.InitDataBase(config.DatabaseConfig{
+		DBType:       "sqlite",
+		DBServerPath: filepath.Join(t.TempDir(), "subscription.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachedb.CacheDb = db
+	t.Cleanup(func() { cachedb.CacheDb = nil })
+}
+
+func TestCheck(t *testing.T) {
+	setupTestDB(t)
+
+	provider := &fakeProvider{pages: map[string][]model.Movie{
+		"1": movies("SSIS-003", "SSIS-002"),
+		"2": movies("SSIS-001"),
+	}}
+	service := NewService(provider, 2)
+
+	var notified []model.Movie
+	service.OnNewMovies(func(sub cachedb.Subscription, found []model.Movie) {
+		notified = append(notified, found...)
+	})
+
+	sub := &cachedb.Subscription{FilterType: "star", FilterValue: "okq", Type: "normal", Magnet: "all"}
+	if err := cachedb.CreateSubscription(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	// 首次检查只记录基线
+	found, err := service.Check(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 0 || !sub.Initialized || provider.calls != 2 {
+		t.Fatalf("baseline check: found = %v, sub = %+v, calls = %d", found, sub, provider.calls)
+	}
+
+	// 新片出现在第一页，第二页不再请求
+	provider.calls = 0
+	provider.pages["1"] = movies("SSIS-004", "SSIS-003")
+	found, err = service.Check(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "SSIS-004" || sub.NewCount != 1 {
+		t.Fatalf("second check: found = %v, newCount = %d", found, sub.NewCount)
+	}
+	if len(notified) != 1 {
+		t.Errorf("handler called with %v", notified)
+	}
+
+	newMovies, err := cachedb.ListNewSubscriptionMovies(sub.ID)
+	if err != nil || len(newMovies) != 1 || newMovies[0].MovieID != "SSIS-004" {
+		t.Fatalf("ListNewSubscriptionMovies() = %+v, err = %v", newMovies, err)
+	}
+
+	// 没有新片时不重复通知
+	found, err = service.Check(sub)
+	if err != nil || len(found) != 0 {
+		t.Errorf("third check: found = %v, err = %v", found, err)
+	}
+
+	if acked, err := cachedb.AckSubscriptionMovies(sub.ID, nil); err != nil || acked != 1 {
+		t.Errorf("AckSubscriptionMovies() = %d, err = %v", acked, err)
+	}
+	if latest, _ := cachedb.GetSubscription(sub.ID); latest == nil || latest.NewCount != 0 {
+		t.Errorf("newCount after ack = %+v", latest)
+	}
+}
+
+func TestMagnetWatcher(t *testing.T) {
+	setupTestDB(t)
+	provider := &fakeProvider{}
+	watcher := NewMagnetWatcher(provider)
+
+	var notified []cachedb.MagnetWatch
+	watcher.OnAvailable(func(watch cachedb.MagnetWatch, magnets []model.Magnet) {
+		notified = append(notified, watch)
+	})
+
+	watch, err := watcher.Watch("SSIS-001", cachedb.WatchNotifyHD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if watch.Fulfilled || watch.GID != "123" || watch.LastCheckedAt == nil {
+		t.Fatalf("Watch() = %+v", watch)
+	}
+
+	// 只有非高清磁力，不满足条件
+	provider.magnets = []model.Magnet{{ID: "a", Link: "magnet:?xt=a"}}
+	if _, err := watcher.Check(watch); err != nil {
+		t.Fatal(err)
+	}
+	if watch.Fulfilled || watch.MagnetCount != 1 || len(notified) != 0 {
+		t.Fatalf("after non-hd magnet: watch = %+v, notified = %d", watch, len(notified))
+	}
+
+	provider.magnets = append(provider.magnets, model.Magnet{ID: "b", Link: "magnet:?xt=b", IsHD: true})
+	watcher.CheckAll(context.Background())
+	latest, _ := cachedb.GetMagnetWatch(watch.ID)
+	if latest == nil || !latest.Fulfilled || !latest.HasHD || len(notified) != 1 {
+		t.Fatalf("after hd magnet: watch = %+v, notified = %d", latest, len(notified))
+	}
+
+	// 已满足的监控不再通知
+	if _, err := watcher.Check(latest); err != nil || len(notified) != 1 {
+		t.Errorf("repeat check notified = %d, err = %v", len(notified), err)
+	}
+
+	// 创建时已满足条件的不通知
+	existing, err := watcher.Watch("SSIS-002", cachedb.WatchNotifyAny)
+	if err != nil || !existing.Fulfilled || len(notified) != 1 {
+		t.Errorf("Watch() on available movie = %+v, err = %v", existing, err)
+	}
+}
@@ -0,0 +1,244 @@
+package torznab
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// Newznab/Torznab 标准分类 (XXX 大类)
+const (
+	CategoryXXX     = 6000
+	CategoryXXXx264 = 6040
+	CategoryXXXSD   = 6080
+)
+
+// 分页限制，对应 caps 中的 limits
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+)
+
+// Torznab 错误码
+// 参考 https://torznab.github.io/spec-1.3-draft/torznab/Specification-v1.3.html
+const (
+	ErrorIncorrectCredentials = 100
+	ErrorMissingParameter     = 200
+	ErrorUnsupportedFunction  = 202
+	ErrorUnknown              = 900
+)
+
+// ==========================================
+// caps (t=caps)
+// ==========================================
+
+type Caps struct {
+	XMLName    xml.Name      `xml:"caps"`
+	Server     CapsServer    `xml:"server"`
+	Limits     CapsLimits    `xml:"limits"`
+	Searching  CapsSearching `xml:"searching"`
+	Categories []Category    `xml:"categories>category"`
+}
+
+type CapsServer struct {
+	Version string `xml:"version,attr"`
+	Title   string `xml:"title,attr"`
+}
+
+type CapsLimits struct {
+	Max     int `xml:"max,attr"`
+	Default int `xml:"default,attr"`
+}
+
+type CapsSearching struct {
+	Search      CapsSearch `xml:"search"`
+	TVSearch    CapsSearch `xml:"tv-search"`
+	MovieSearch CapsSearch `xml:"movie-search"`
+}
+
+type CapsSearch struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+type Category struct {
+	ID      int      `xml:"id,attr"`
+	Name    string   `xml:"name,attr"`
+	Subcats []Subcat `xml:"subcat"`
+}
+
+type Subcat struct {
+	ID   int    `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+// NewCaps 生成本服务的能力描述
+func NewCaps(title string) *Caps {
+	return &Caps{
+		Server: CapsServer{Version: "1.0", Title: title},
+		Limits: CapsLimits{Max: MaxLimit, Default: DefaultLimit},
+		Searching: CapsSearching{
+			Search:      CapsSearch{Available: "yes", SupportedParams: "q"},
+			TVSearch:    CapsSearch{Available: "no", SupportedParams: "q"},
+			MovieSearch: CapsSearch{Available: "yes", SupportedParams: "q"},
+		},
+		Categories: []Category{
+			{
+				ID:   CategoryXXX,
+				Name: "XXX",
+				Subcats: []Subcat{
+					{ID: CategoryXXXx264, Name: "XXX/x264"},
+					{ID: CategoryXXXSD, Name: "XXX/SD"},
+				},
+			},
+		},
+	}
+}
+
+// ==========================================
+// 搜索结果 (RSS)
+// ==========================================
+
+type Feed struct {
+	XMLName      xml.Name `xml:"rss"`
+	Version      string   `xml:"version,attr"`
+	XmlnsAtom    string   `xml:"xmlns:atom,attr"`
+	XmlnsTorznab string   `xml:"xmlns:torznab,attr"`
+	Channel      Channel  `xml:"channel"`
+}
+
+type Channel struct {
+	Title       string   `xml:"title"`
+	Description string   `xml:"description"`
+	Link        string   `xml:"link"`
+	Response    Response `xml:"torznab:response"`
+	Items       []Item   `xml:"item"`
+}
+
+type Response struct {
+	Offset int `xml:"offset,attr"`
+	Total  int `xml:"total,attr"`
+}
+
+type Item struct {
+	Title     string    `xml:"title"`
+	GUID      string    `xml:"guid"`
+	Link      string    `xml:"link"`
+	Comments  string    `xml:"comments,omitempty"`
+	PubDate   string    `xml:"pubDate,omitempty"`
+	Size      int64     `xml:"size"`
+	Category  int       `xml:"category"`
+	Enclosure Enclosure `xml:"enclosure"`
+	Attrs     []Attr    `xml:"torznab:attr"`
+}
+
+type Enclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type Attr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// NewFeed 创建一个空的结果集
+func NewFeed(title, link string, offset int) *Feed {
+	return &Feed{
+		Version:      "2.0",
+		XmlnsAtom:    "http://www.w3.org/2005/Atom",
+		XmlnsTorznab: "http://torznab.com/schemas/2015/feed",
+		Channel: Channel{
+			Title:       title,
+			Description: title + " torznab feed",
+			Link:        link,
+			Response:    Response{Offset: offset},
+			Items:       []Item{},
+		},
+	}
+}
+
+// Add 追加结果并更新总数
+func (f *Feed) Add(items ...Item) {
+	f.Channel.Items = append(f.Channel.Items, items...)
+	f.Channel.Response.Total = len(f.Channel.Items)
+}
+
+// ItemFromMagnet 将影片和其中一条磁力链接转换为一条 Torznab 结果
+// detailLink 为影片详情页地址，作为 comments 返回
+func ItemFromMagnet(movie model.Movie, magnet model.Magnet, detailLink string) Item {
+	title := strings.TrimSpace(magnet.Title)
+	if title == "" {
+		title = movie.Title
+	}
+	// 保证标题中带有番号，方便下游工具解析
+	if movie.ID != "" && !strings.Contains(strings.ToUpper(title), strings.ToUpper(movie.ID)) {
+		title = movie.ID + " " + title
+	}
+
+	category := CategoryXXXSD
+	if magnet.IsHD {
+		category = CategoryXXXx264
+	}
+
+	attrs := []Attr{
+		{Name: "category", Value: strconv.Itoa(CategoryXXX)},
+		{Name: "category", Value: strconv.Itoa(category)},
+		{Name: "size", Value: strconv.FormatInt(magnet.NumberSize, 10)},
+		{Name: "infohash", Value: strings.ToLower(magnet.ID)},
+		{Name: "magneturl", Value: magnet.Link},
+	}
+	if magnet.IsHD {
+		attrs = append(attrs, Attr{Name: "tag", Value: "hd"})
+	}
+	if magnet.HasSubtitle {
+		attrs = append(attrs, Attr{Name: "tag", Value: "subtitles"})
+	}
+
+	return Item{
+		Title:    title,
+		GUID:     strings.ToLower(magnet.ID),
+		Link:     magnet.Link,
+		Comments: detailLink,
+		PubDate:  formatPubDate(magnet.ShareDate, movie.Date),
+		Size:     magnet.NumberSize,
+		Category: category,
+		Enclosure: Enclosure{
+			URL:    magnet.Link,
+			Length: magnet.NumberSize,
+			Type:   "application/x-bittorrent",
+		},
+		Attrs: attrs,
+	}
+}
+
+// formatPubDate 将 2006-01-02 格式的日期转换为 RSS 使用的 RFC1123Z
+// 优先使用磁力分享日期，没有时退回影片发行日期
+func formatPubDate(dates ...string) string {
+	for _, d := range dates {
+		if t, err := time.Parse("2006-01-02", strings.TrimSpace(d)); err == nil {
+			return t.Format(time.RFC1123Z)
+		}
+	}
+	return ""
+}
+
+// ==========================================
+// 错误
+// ==========================================
+
+type Error struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+// NewError 创建 Torznab 错误
+func NewError(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Description: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,70 @@
+package torznab
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/fireinrain/javbus-api/model"
+)
+
+func TestItemFromMagnet(t *testing.T) {
+	movie := model.Movie{ID: "SSIS-406", Title: "SSIS-406 title", Date: "2022-05-20"}
+	magnet := model.Magnet{
+		ID:          "ABCDEF0123456789",
+		Link:        "magnet:?xt=urn:btih:ABCDEF0123456789",
+		IsHD:        true,
+		HasSubtitle: true,
+		Title:       "ssis406ch",
+		Size:        "5.2GB",
+		NumberSize:  5583457484,
+		ShareDate:   "2022-05-21",
+	}
+
+	item := ItemFromMagnet(movie, magnet, "https://www.javbus.com/SSIS-406")
+
+	if !strings.HasPrefix(item.Title, "SSIS-406 ") {
+		t.Errorf("title should be prefixed with movie id, got %q", item.Title)
+	}
+	if item.GUID != "abcdef0123456789" {
+		t.Errorf("guid = %q", item.GUID)
+	}
+	if item.Category != CategoryXXXx264 {
+		t.Errorf("category = %d, want %d", item.Category, CategoryXXXx264)
+	}
+	if item.PubDate != "Sat, 21 May 2022 00:00:00 +0000" {
+		t.Errorf("pubDate = %q", item.PubDate)
+	}
+
+	attrs := map[string][]string{}
+	for _, a := range item.Attrs {
+		attrs[a.Name] = append(attrs[a.Name], a.Value)
+	}
+	if attrs["infohash"][0] != "abcdef0123456789" || attrs["size"][0] != "5583457484" {
+		t.Errorf("unexpected attrs: %v", attrs)
+	}
+	if len(attrs["tag"]) != 2 {
+		t.Errorf("expected hd and subtitles tags, got %v", attrs["tag"])
+	}
+}
+
+func TestFeedXML(t *testing.T) {
+	feed := NewFeed("javbus-api", "http://localhost:3000/torznab/api", 0)
+	feed.Add(ItemFromMagnet(model.Movie{ID: "ABP-123"}, model.Magnet{ID: "aa", Link: "magnet:?xt=urn:btih:aa"}, ""))
+
+	data, err := xml.Marshal(feed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(data)
+	for _, want := range []string{
+		`xmlns:torznab="http://torznab.com/schemas/2015/feed"`,
+		`<torznab:response offset="0" total="1">`,
+		`<torznab:attr name="infohash" value="aa">`,
+		`type="application/x-bittorrent"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("feed xml missing %s\n%s", want, out)
+		}
+	}
+}
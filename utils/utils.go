@@ -2,13 +2,11 @@ package utils
 
 import (
 	"strings"
-
-	"github.com/fireinrain/javbus-api/consts"
 )
 
-// FormatImageURL 格式化图片链接
+// FormatImageURL 格式化图片链接，相对路径补全为 baseURL 下的绝对路径
 // 对应 TS: formatImageUrl(url?: string)
-func FormatImageURL(url string) string {
+func FormatImageURL(url string, baseURL string) string {
 	// 1. 对应 TS 的 url && ... (判空)
 	if url == "" {
 		return ""
@@ -17,7 +15,7 @@ func FormatImageURL(url string) string {
 	// 2. 对应 TS 的 !/^http/.test(url)
 	// 使用 HasPrefix 替代正则，性能更好
 	if !strings.HasPrefix(url, "http") {
-		return consts.JavBusURL + url
+		return strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(url, "/")
 	}
 
 	return url
@@ -0,0 +1,95 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 时间戳与当前时间相差超过该值的请求视为重放
+const maxTimestampSkew = 5 * time.Minute
+
+// 接收端最多保留的事件数
+const maxReceivedEvents = 100
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpiredTimestamp = errors.New("timestamp expired")
+)
+
+// ReceivedEvent 接收端收到的事件
+type ReceivedEvent struct {
+	ReceivedAt time.Time       `json:"receivedAt"`
+	Event      string          `json:"event"`
+	Delivery   string          `json:"delivery"`
+	Verified   bool            `json:"verified"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Receiver 本地测试用的 webhook 接收端，校验签名并在内存中保留最近的事件
+type Receiver struct {
+	secret string
+
+	mu     sync.RWMutex
+	events []ReceivedEvent
+}
+
+// NewReceiver 创建接收端，secret 为空时不校验签名
+func NewReceiver(secret string) *Receiver {
+	return &Receiver{secret: secret}
+}
+
+// Receive 校验并记录一次请求
+func (r *Receiver) Receive(header http.Header, body []byte) (*ReceivedEvent, error) {
+	event := ReceivedEvent{
+		ReceivedAt: time.Now(),
+		Event:      header.Get(HeaderEvent),
+		Delivery:   header.Get(HeaderDelivery),
+	}
+
+	if r.secret != "" {
+		timestamp := header.Get(HeaderTimestamp)
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || absDuration(time.Since(time.Unix(ts, 0))) > maxTimestampSkew {
+			return nil, ErrExpiredTimestamp
+		}
+		if !Verify(r.secret, timestamp, header.Get(HeaderSignature), body) {
+			return nil, ErrInvalidSignature
+		}
+		event.Verified = true
+	}
+
+	if !json.Valid(body) {
+		return nil, errors.New("payload is not valid json")
+	}
+	event.Payload = json.RawMessage(append([]byte(nil), body...))
+
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	if len(r.events) > maxReceivedEvents {
+		r.events = r.events[len(r.events)-maxReceivedEvents:]
+	}
+	r.mu.Unlock()
+	return &event, nil
+}
+
+// Events 返回收到的事件，最新的在前
+func (r *Receiver) Events() []ReceivedEvent {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]ReceivedEvent, 0, len(r.events))
+	for i := len(r.events) - 1; i >= 0; i-- {
+		list = append(list, r.events[i])
+	}
+	return list
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
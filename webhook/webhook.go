@@ -0,0 +1,320 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	mrand "math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+	"github.com/fireinrain/javbus-api/model"
+)
+
+// 事件类型
+const (
+	EventSubscriptionNewMovies = "subscription.new_movies"
+	EventMagnetAvailable       = "magnet.available"
+	EventPing                  = "ping"
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-Javbus-Event"
+	HeaderDelivery  = "X-Javbus-Delivery"
+	HeaderTimestamp = "X-Javbus-Timestamp"
+	HeaderSignature = "X-Javbus-Signature"
+)
+
+// 重试间隔: 2s, 4s, 8s ... 最长 10 分钟
+var (
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = 10 * time.Minute
+)
+
+// 同时进行的投递数
+const maxConcurrentDeliveries = 4
+
+// ErrDeliveryPending 投递仍在进行或等待重试，不能重新投递
+var ErrDeliveryPending = errors.New("delivery is still pending")
+
+// Event 投递给 webhook 的事件
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// SubscriptionNewMoviesData 订阅发现新片
+type SubscriptionNewMoviesData struct {
+	Subscription cachedb.Subscription `json:"subscription"`
+	Movies       []model.Movie        `json:"movies"`
+}
+
+// MagnetAvailableData 监控的影片出现了满足条件的磁力链接
+type MagnetAvailableData struct {
+	Watch   cachedb.MagnetWatch `json:"watch"`
+	Movie   model.Movie         `json:"movie"`
+	Magnets []model.Magnet      `json:"magnets"`
+}
+
+// Dispatcher 把事件签名后投递到配置的地址，失败时按指数退避重试并记录投递日志
+type Dispatcher struct {
+	cfg    config.WebhookConfig
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	sem    chan struct{}
+
+	redeliverMu sync.Mutex // 避免同一记录被并发重新投递
+}
+
+// NewDispatcher 创建投递器
+func NewDispatcher(cfg config.WebhookConfig) *Dispatcher {
+	if cfg.MaxRetries < 1 {
+		cfg.MaxRetries = 1
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		cfg:    cfg,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		ctx:    ctx,
+		cancel: cancel,
+		sem:    make(chan struct{}, maxConcurrentDeliveries),
+	}
+}
+
+// Enabled 是否配置了 webhook 地址
+func (d *Dispatcher) Enabled() bool {
+	return len(d.cfg.URLs) > 0
+}
+
+// Dispatch 为每个地址创建投递记录并在后台投递
+func (d *Dispatcher) Dispatch(eventType string, data interface{}) ([]cachedb.WebhookDelivery, error) {
+	if !d.Enabled() {
+		return nil, nil
+	}
+
+	event := Event{ID: newEventID(), Type: eventType, CreatedAt: time.Now(), Data: data}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]cachedb.WebhookDelivery, 0, len(d.cfg.URLs))
+	for _, url := range d.cfg.URLs {
+		delivery := cachedb.WebhookDelivery{
+			EventID:   event.ID,
+			EventType: eventType,
+			URL:       url,
+			Payload:   string(payload),
+			Status:    cachedb.DeliveryStatusPending,
+		}
+		if err := cachedb.SaveDelivery(&delivery); err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+		d.start(delivery)
+	}
+	return deliveries, nil
+}
+
+// Redeliver 重新投递一条记录 (重置重试次数)，记录仍为 pending 时返回 ErrDeliveryPending
+func (d *Dispatcher) Redeliver(id uint) (*cachedb.WebhookDelivery, error) {
+	d.redeliverMu.Lock()
+	defer d.redeliverMu.Unlock()
+
+	delivery, err := cachedb.GetDelivery(id)
+	if err != nil || delivery == nil {
+		return delivery, err
+	}
+	if delivery.Status == cachedb.DeliveryStatusPending {
+		return delivery, ErrDeliveryPending
+	}
+	delivery.Status = cachedb.DeliveryStatusPending
+	delivery.Attempts = 0
+	delivery.NextRetryAt = nil
+	if err := cachedb.SaveDelivery(delivery); err != nil {
+		return nil, err
+	}
+	d.start(*delivery)
+	return delivery, nil
+}
+
+// Resume 继续投递上次退出时未完成的记录
+func (d *Dispatcher) Resume() {
+	pending, err := cachedb.ListPendingDeliveries()
+	if err != nil {
+		log.Printf("读取未完成的 webhook 投递失败: %v", err)
+		return
+	}
+	for _, delivery := range pending {
+		d.start(delivery)
+	}
+	if len(pending) > 0 {
+		log.Printf("继续投递 %d 条 webhook", len(pending))
+	}
+}
+
+// Stop 停止重试并等待正在进行的请求结束，未完成的记录保持 pending 状态
+func (d *Dispatcher) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) start(delivery cachedb.WebhookDelivery) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(&delivery)
+	}()
+}
+
+// deliver 投递直到成功、达到最大次数或遇到不可重试的响应
+func (d *Dispatcher) deliver(delivery *cachedb.WebhookDelivery) {
+	for delivery.Attempts < d.cfg.MaxRetries {
+		if delivery.NextRetryAt != nil {
+			if !d.sleep(time.Until(*delivery.NextRetryAt)) {
+				return
+			}
+		}
+
+		select {
+		case d.sem <- struct{}{}:
+		case <-d.ctx.Done():
+			return
+		}
+		statusCode, err := d.send(delivery)
+		<-d.sem
+
+		now := time.Now()
+		delivery.Attempts++
+		delivery.LastStatusCode = statusCode
+		delivery.NextRetryAt = nil
+		switch {
+		case err == nil:
+			delivery.Status = cachedb.DeliveryStatusSuccess
+			delivery.LastError = ""
+			delivery.DeliveredAt = &now
+		case !retryable(statusCode) || delivery.Attempts >= d.cfg.MaxRetries:
+			delivery.Status = cachedb.DeliveryStatusFailed
+			delivery.LastError = err.Error()
+		default:
+			next := now.Add(backoff(delivery.Attempts))
+			delivery.LastError = err.Error()
+			delivery.NextRetryAt = &next
+		}
+
+		if saveErr := cachedb.SaveDelivery(delivery); saveErr != nil {
+			log.Printf("更新 webhook 投递记录失败 %d: %v", delivery.ID, saveErr)
+		}
+		if delivery.Status != cachedb.DeliveryStatusPending {
+			if delivery.Status == cachedb.DeliveryStatusFailed {
+				log.Printf("webhook 投递失败 %s -> %s: %s", delivery.EventType, delivery.URL, delivery.LastError)
+			}
+			return
+		}
+	}
+}
+
+// send 发送一次请求，非 2xx 响应返回错误
+func (d *Dispatcher) send(delivery *cachedb.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "javbus-api-webhook")
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.EventID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	if d.cfg.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(d.cfg.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("status code %d: %s", resp.StatusCode, bytes.TrimSpace(respBody))
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) sleep(wait time.Duration) bool {
+	if wait <= 0 {
+		return d.ctx.Err() == nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-d.ctx.Done():
+		return false
+	}
+}
+
+// retryable 网络错误、408、429 和 5xx 可以重试，其余 4xx 说明请求本身有问题
+func retryable(statusCode int) bool {
+	return statusCode == 0 ||
+		statusCode == http.StatusRequestTimeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode >= 500
+}
+
+// backoff 第 n 次失败后的等待时间，带 ±20% 抖动
+func backoff(attempts int) time.Duration {
+	delay := float64(retryBaseDelay) * math.Pow(2, float64(attempts-1))
+	if delay > float64(retryMaxDelay) {
+		delay = float64(retryMaxDelay)
+	}
+	jitter := delay * 0.2 * (mrand.Float64()*2 - 1)
+	return time.Duration(delay + jitter)
+}
+
+// Sign 计算签名: sha256=HMAC-SHA256(secret, timestamp + "." + body)
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名
+func Verify(secret, timestamp, signature string, body []byte) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func newEventID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fireinrain/javbus-api/cachedb"
+	"github.com/fireinrain/javbus-api/config"
+)
+
+func setupTestDB(t *testing.T) {
+	t.Helper()
+	db, err := cachedb.InitDataBase(config.DatabaseConfig{
+		DBType:       "sqlite",
+		DBServerPath: filepath.Join(t.TempDir(), "webhook.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cachedb.CacheDb = db
+	t.Cleanup(func() { cachedb.CacheDb = nil })
+}
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":"evt_1"}`)
+	sig := Sign("secret", "1700000000", body)
+	if !Verify("secret", "1700000000", sig, body) {
+		t.Error("valid signature rejected")
+	}
+	if Verify("secret", "1700000001", sig, body) || Verify("other", "1700000000", sig, body) {
+		t.Error("signature with wrong timestamp or secret accepted")
+	}
+}
+
+func TestDispatchRetry(t *testing.T) {
+	setupTestDB(t)
+	retryBaseDelay = 10 * time.Millisecond
+
+	receiver := NewReceiver("secret")
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次返回 503，之后正常接收
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if _, err := receiver.Receive(r.Header, body); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(config.WebhookConfig{URLs: []string{server.URL}, Secret: "secret", MaxRetries: 3})
+	deliveries, err := d.Dispatch(EventPing, map[string]string{"message": "pong"})
+	if err != nil || len(deliveries) != 1 {
+		t.Fatalf("Dispatch() = %v, %v", deliveries, err)
+	}
+	d.wg.Wait()
+
+	delivery, err := cachedb.GetDelivery(deliveries[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != cachedb.DeliveryStatusSuccess || delivery.Attempts != 2 || delivery.LastStatusCode != http.StatusNoContent {
+		t.Errorf("delivery = %+v", delivery)
+	}
+
+	events := receiver.Events()
+	if len(events) != 1 || !events[0].Verified || events[0].Event != EventPing {
+		t.Errorf("received events = %+v", events)
+	}
+}
+
+func TestDispatchNotRetryable(t *testing.T) {
+	setupTestDB(t)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 重新投递的请求等到检查完重复投递后再返回
+		if calls.Add(1) > 1 {
+			<-release
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(config.WebhookConfig{URLs: []string{server.URL}, MaxRetries: 5})
+	deliveries, err := d.Dispatch(EventPing, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.wg.Wait()
+
+	delivery, _ := cachedb.GetDelivery(deliveries[0].ID)
+	if delivery.Status != cachedb.DeliveryStatusFailed || calls.Load() != 1 {
+		t.Errorf("delivery = %+v, calls = %d", delivery, calls.Load())
+	}
+
+	// 重新投递进行中时不能再次重新投递
+	if _, err := d.Redeliver(delivery.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Redeliver(delivery.ID); !errors.Is(err, ErrDeliveryPending) {
+		t.Errorf("second Redeliver() error = %v, want ErrDeliveryPending", err)
+	}
+	close(release)
+	d.wg.Wait()
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d after redeliver, want 2", calls.Load())
+	}
+}
+
+func TestReceiverRejectsBadSignature(t *testing.T) {
+	receiver := NewReceiver("secret")
+	header := http.Header{}
+	header.Set(HeaderTimestamp, "1")
+	if _, err := receiver.Receive(header, []byte(`{}`)); err != ErrExpiredTimestamp {
+		t.Errorf("expired timestamp err = %v", err)
+	}
+
+	now := time.Now().Unix()
+	header.Set(HeaderTimestamp, strconv.FormatInt(now, 10))
+	header.Set(HeaderSignature, "sha256=00")
+	if _, err := receiver.Receive(header, []byte(`{}`)); err != ErrInvalidSignature {
+		t.Errorf("bad signature err = %v", err)
+	}
+}